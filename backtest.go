@@ -0,0 +1,359 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Shape of the backtest report: calibration buckets of 10 percentage points and goal counts up to backtestMaxGoals or more
+const (
+	calibrationBuckets = 10
+	backtestMaxGoals   = 6
+)
+
+// minLogLossProbability keeps the log-loss finite when an engine gave a result no chance at all
+const minLogLossProbability = 1e-15
+
+// BacktestReport scores the pre-match probabilities of every engine against the results of a season
+type BacktestReport struct {
+	Matches  int              `json:"matches"`
+	Outcomes OutcomeCounts    `json:"outcomes"`
+	Engines  []EngineBacktest `json:"engines"`
+}
+
+// OutcomeCounts counts the results of the backtested matches
+type OutcomeCounts struct {
+	HomeWins int `json:"home_wins"`
+	Draws    int `json:"draws"`
+	AwayWins int `json:"away_wins"`
+}
+
+// EngineBacktest is the score of one match engine, lower Brier score and log-loss are better
+type EngineBacktest struct {
+	Engine      string              `json:"engine"`
+	BrierScore  float64             `json:"brier_score"` // mean over matches of the squared errors of the three outcomes, 0 to 2
+	LogLoss     float64             `json:"log_loss"`    // mean negative natural log of the probability given to the actual outcome
+	Calibration []CalibrationBucket `json:"calibration"`
+	Goals       GoalComparison      `json:"goals"`
+}
+
+// CalibrationBucket compares the outcome probabilities an engine gave within a range with how often those outcomes happened.
+// Every match adds its home win, draw and away win probabilities, percentages throughout.
+type CalibrationBucket struct {
+	From        float64 `json:"from"`
+	To          float64 `json:"to"`
+	Predictions int     `json:"predictions"`
+	Predicted   float64 `json:"predicted"` // mean probability given
+	Observed    float64 `json:"observed"`  // share of those predictions that came true
+}
+
+// GoalComparison compares the goals an engine expected with the goals scored
+type GoalComparison struct {
+	PredictedHome float64         `json:"predicted_home"`
+	ActualHome    float64         `json:"actual_home"`
+	PredictedAway float64         `json:"predicted_away"`
+	ActualAway    float64         `json:"actual_away"`
+	Distribution  []GoalFrequency `json:"distribution"` // goals of one side in one match, both sides pooled
+}
+
+// GoalFrequency is the predicted and actual share (%) of team performances with a number of goals
+type GoalFrequency struct {
+	Goals     int     `json:"goals"`
+	OrMore    bool    `json:"or_more,omitempty"`
+	Predicted float64 `json:"predicted"`
+	Actual    float64 `json:"actual"`
+}
+
+// backtestEngines returns every engine to compare. The fitted engine is a private one refit before every week,
+// the shared one may have been fit on the very matches being predicted.
+func backtestEngines() []MatchEngine {
+	var engines []MatchEngine
+	for _, name := range []string{"legacy", "poisson", "dixon-coles"} {
+		engine, _ := matchEngineByName(name)
+		engines = append(engines, engine)
+	}
+	return append(engines, &fittedEngine{})
+}
+
+// Backtest replays the played matches of the season and scores the probabilities every engine would have given them
+func (s *MyMatchService) Backtest() (BacktestReport, error) {
+	teams, err := s.repo.Teams()
+	if err != nil {
+		return BacktestReport{}, err
+	}
+	matches, err := s.repo.Matches()
+	if err != nil {
+		return BacktestReport{}, err
+	}
+	return backtestSeason(teams, matches, s.settings, backtestEngines()), nil
+}
+
+// backtestSeason predicts every played match from the state of the season before its week, so ratings and fitted engines
+// only know earlier results. Matches decided in extra time are left out, the engines predict 90 minutes.
+func backtestSeason(teams []Team, matches []Match, settings LeagueSettings, engines []MatchEngine) BacktestReport {
+	played := slices.DeleteFunc(slices.Clone(matches), func(m Match) bool {
+		return !m.Played || m.ExtraTime || m.HomeGoals == nil || m.AwayGoals == nil
+	})
+	slices.SortStableFunc(played, func(a, b Match) int { return a.Week - b.Week })
+
+	scores := make([]*engineScore, len(engines))
+	for i := range scores {
+		scores[i] = newEngineScore()
+	}
+	report := BacktestReport{Matches: len(played)}
+
+	var byID map[int]Team
+	week := 0
+	for _, m := range played {
+		if m.Week != week || byID == nil {
+			week = m.Week
+			// The season as it stood before this week
+			before := slices.Clone(matches)
+			for i := range before {
+				if before[i].Week >= week {
+					before[i].Played = false
+				}
+			}
+			byID = teamsByID(computeStandings(teams, before, settings))
+			for _, engine := range engines {
+				if fitted, ok := engine.(*fittedEngine); ok {
+					fitted.use(fitBefore(teams, played, week))
+				}
+			}
+		}
+
+		homeGoals, awayGoals := *m.HomeGoals, *m.AwayGoals
+		switch {
+		case homeGoals > awayGoals:
+			report.Outcomes.HomeWins++
+		case homeGoals == awayGoals:
+			report.Outcomes.Draws++
+		default:
+			report.Outcomes.AwayWins++
+		}
+		for i, engine := range engines {
+			scores[i].add(engine.ScoreProbabilities(byID[m.HomeTeamID], byID[m.AwayTeamID]), homeGoals, awayGoals)
+		}
+	}
+
+	for i, engine := range engines {
+		report.Engines = append(report.Engines, scores[i].result(engine.Name()))
+	}
+	return report
+}
+
+// fitBefore fits the fitted engine to the played matches before a week, with none yet it plays like the Poisson engine.
+// Matches of teams that are not in the season are left out.
+func fitBefore(teams []Team, played []Match, week int) FittedModel {
+	index := make(map[int]int, len(teams))
+	for i, t := range teams {
+		index[t.ID] = i
+	}
+	var matches []fitMatch
+	for _, m := range played {
+		home, homeOK := index[m.HomeTeamID]
+		away, awayOK := index[m.AwayTeamID]
+		if m.Week < week && homeOK && awayOK {
+			matches = append(matches, fitMatch{home, away, *m.HomeGoals, *m.AwayGoals})
+		}
+	}
+	if len(matches) == 0 {
+		return FittedModel{}
+	}
+	_, model := fitStrengths(teams, matches)
+	return model
+}
+
+// engineScore accumulates the predictions of one engine
+type engineScore struct {
+	matches                      int
+	brier, logLoss               float64
+	bucketCount                  []int
+	bucketPredicted, bucketTrue  []float64
+	predictedHome, predictedAway float64
+	actualHome, actualAway       float64
+	predictedGoals, actualGoals  []float64 // by goals of one side, the last entry counts that many or more
+}
+
+// newEngineScore creates an empty score
+func newEngineScore() *engineScore {
+	return &engineScore{
+		bucketCount:     make([]int, calibrationBuckets),
+		bucketPredicted: make([]float64, calibrationBuckets),
+		bucketTrue:      make([]float64, calibrationBuckets),
+		predictedGoals:  make([]float64, backtestMaxGoals+1),
+		actualGoals:     make([]float64, backtestMaxGoals+1),
+	}
+}
+
+// add scores the scoreline matrix an engine gave a match against its result.
+// The matrix stops at maxMatrixGoals per side, so it is rescaled to add up to one.
+func (s *engineScore) add(matrix [][]float64, homeGoals, awayGoals int) {
+	var outcome [3]float64 // home win, draw, away win
+	var expectedHome, expectedAway, total float64
+	goals := make([]float64, backtestMaxGoals+1)
+	for i, row := range matrix {
+		for j, p := range row {
+			switch {
+			case i > j:
+				outcome[0] += p
+			case i == j:
+				outcome[1] += p
+			default:
+				outcome[2] += p
+			}
+			expectedHome += float64(i) * p
+			expectedAway += float64(j) * p
+			total += p
+			goals[min(i, backtestMaxGoals)] += p
+			goals[min(j, backtestMaxGoals)] += p
+		}
+	}
+	for g := range goals {
+		s.predictedGoals[g] += goals[g] / total
+	}
+
+	actual := 2
+	if homeGoals > awayGoals {
+		actual = 0
+	} else if homeGoals == awayGoals {
+		actual = 1
+	}
+	for k := range outcome {
+		outcome[k] /= total
+		happened := 0.0
+		if k == actual {
+			happened = 1
+		}
+		s.brier += (outcome[k] - happened) * (outcome[k] - happened)
+
+		bucket := min(int(outcome[k]*calibrationBuckets), calibrationBuckets-1)
+		s.bucketCount[bucket]++
+		s.bucketPredicted[bucket] += outcome[k]
+		s.bucketTrue[bucket] += happened
+	}
+	s.logLoss -= math.Log(math.Max(outcome[actual], minLogLossProbability))
+
+	s.matches++
+	s.predictedHome += expectedHome / total
+	s.predictedAway += expectedAway / total
+	s.actualHome += float64(homeGoals)
+	s.actualAway += float64(awayGoals)
+	s.actualGoals[min(homeGoals, backtestMaxGoals)]++
+	s.actualGoals[min(awayGoals, backtestMaxGoals)]++
+}
+
+// result turns the accumulated predictions into the report of one engine
+func (s *engineScore) result(name string) EngineBacktest {
+	round := func(v float64) float64 { return math.Round(v*10000) / 10000 }
+	result := EngineBacktest{Engine: name, Calibration: []CalibrationBucket{}}
+	if s.matches == 0 {
+		return result
+	}
+	n := float64(s.matches)
+	result.BrierScore = round(s.brier / n)
+	result.LogLoss = round(s.logLoss / n)
+
+	for b := range s.bucketCount {
+		if s.bucketCount[b] == 0 {
+			continue
+		}
+		count := float64(s.bucketCount[b])
+		result.Calibration = append(result.Calibration, CalibrationBucket{
+			From:        float64(b * 100 / calibrationBuckets),
+			To:          float64((b + 1) * 100 / calibrationBuckets),
+			Predictions: s.bucketCount[b],
+			Predicted:   roundPercentage(s.bucketPredicted[b] / count),
+			Observed:    roundPercentage(s.bucketTrue[b] / count),
+		})
+	}
+
+	result.Goals = GoalComparison{
+		PredictedHome: round(s.predictedHome / n),
+		ActualHome:    round(s.actualHome / n),
+		PredictedAway: round(s.predictedAway / n),
+		ActualAway:    round(s.actualAway / n),
+	}
+	predictedTotal, actualTotal := 0.0, 0.0
+	for g := range s.predictedGoals {
+		predictedTotal += s.predictedGoals[g]
+		actualTotal += s.actualGoals[g]
+	}
+	for g := range s.predictedGoals {
+		result.Goals.Distribution = append(result.Goals.Distribution, GoalFrequency{
+			Goals:     g,
+			OrMore:    g == backtestMaxGoals,
+			Predicted: roundPercentage(s.predictedGoals[g] / predictedTotal),
+			Actual:    roundPercentage(s.actualGoals[g] / actualTotal),
+		})
+	}
+	return result
+}
+
+// runBacktest prints the backtest of a season given by id, or of the current season of the first league
+func runBacktest(leagueService *MyLeagueService, seasonArg string) error {
+	var season Season
+	var err error
+	if seasonArg == "" {
+		season, err = leagueService.CurrentSeason(1)
+	} else {
+		id, convErr := strconv.Atoi(seasonArg)
+		if convErr != nil {
+			return fmt.Errorf("%w: season must be a number, got %q", errInvalidInput, seasonArg)
+		}
+		season, err = leagueService.store.Season(id)
+	}
+	if err != nil {
+		return err
+	}
+	services, err := leagueService.SeasonServices(season.LeagueID, season.ID)
+	if err != nil {
+		return err
+	}
+	report, err := services.matches.Backtest()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("league=%d season=%d ", season.LeagueID, season.ID)
+	printBacktest(report)
+	return nil
+}
+
+// printBacktest writes a report as text, one block per engine
+func printBacktest(report BacktestReport) {
+	fmt.Printf("matches=%d home_wins=%d draws=%d away_wins=%d\n",
+		report.Matches, report.Outcomes.HomeWins, report.Outcomes.Draws, report.Outcomes.AwayWins)
+	for _, e := range report.Engines {
+		fmt.Printf("\n%s: brier=%.4f log_loss=%.4f goals home %.2f (actual %.2f) away %.2f (actual %.2f)\n",
+			e.Engine, e.BrierScore, e.LogLoss, e.Goals.PredictedHome, e.Goals.ActualHome, e.Goals.PredictedAway, e.Goals.ActualAway)
+		fmt.Printf("  %-10s %11s %10s %9s\n", "bucket", "predictions", "predicted", "observed")
+		for _, b := range e.Calibration {
+			fmt.Printf("  %3.0f-%3.0f%%   %11d %9.1f%% %8.1f%%\n", b.From, b.To, b.Predictions, b.Predicted, b.Observed)
+		}
+		fmt.Printf("  %-10s %11s %10s\n", "goals", "predicted", "actual")
+		for _, g := range e.Goals.Distribution {
+			label := fmt.Sprint(g.Goals)
+			if g.OrMore {
+				label += "+"
+			}
+			fmt.Printf("  %-10s %10.1f%% %9.1f%%\n", label, g.Predicted, g.Actual)
+		}
+	}
+}
+
+// BacktestHandler handles the request to score every match engine against the played matches of the season
+func BacktestHandler(matchService MatchService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report, err := matchService.Backtest()
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
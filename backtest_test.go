@@ -0,0 +1,88 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFitBeforeOnlySeesEarlierWeeks(t *testing.T) {
+	settings := benchmarkSettings()
+	teams, matches, currentWeek := benchmarkLeague(settings)
+	var played []Match
+	for _, m := range matches {
+		if m.Played {
+			played = append(played, m)
+		}
+	}
+
+	if model := fitBefore(teams, played, 1); len(model.Teams) != 0 {
+		t.Errorf("the fit before week 1 knows %d teams, want none", len(model.Teams))
+	}
+
+	// Changing the results of the week being predicted must not change the fit
+	before := fitBefore(teams, played, currentWeek)
+	for i := range played {
+		if played[i].Week == currentWeek {
+			goals := *played[i].HomeGoals + 5
+			played[i].HomeGoals = &goals
+		}
+	}
+	if after := fitBefore(teams, played, currentWeek); !reflect.DeepEqual(before, after) {
+		t.Errorf("the fit before week %d changed with the results of that week", currentWeek)
+	}
+	if after := fitBefore(teams, played, currentWeek+1); reflect.DeepEqual(before, after) {
+		t.Errorf("the fit before week %d ignores the results of week %d", currentWeek+1, currentWeek)
+	}
+}
+
+func TestBacktestScoresEveryEngine(t *testing.T) {
+	settings := benchmarkSettings()
+	teams, matches, _ := benchmarkLeague(settings)
+	report := backtestSeason(teams, matches, settings, backtestEngines())
+
+	played := 0
+	for _, m := range matches {
+		if m.Played {
+			played++
+		}
+	}
+	if report.Matches != played || report.Outcomes.HomeWins+report.Outcomes.Draws+report.Outcomes.AwayWins != played {
+		t.Fatalf("backtested %d matches with outcomes %+v, want %d", report.Matches, report.Outcomes, played)
+	}
+	var names []string
+	for _, e := range report.Engines {
+		names = append(names, e.Engine)
+		if e.BrierScore <= 0 || e.BrierScore >= 2 || e.LogLoss <= 0 {
+			t.Errorf("%s: brier score %v and log-loss %v out of range", e.Engine, e.BrierScore, e.LogLoss)
+		}
+	}
+	if want := []string{"legacy", "poisson", "dixon-coles", "fitted"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("engines %v, want %v", names, want)
+	}
+}
+
+func TestBacktestRefitsTheFittedEngineEveryWeek(t *testing.T) {
+	settings := benchmarkSettings()
+	teams, matches, _ := benchmarkLeague(settings)
+	report := backtestSeason(teams, matches, settings, backtestEngines())
+
+	// Without a refit the fitted engine falls back to the Poisson engine and scores exactly like it
+	byName := make(map[string]EngineBacktest)
+	for _, e := range report.Engines {
+		byName[e.Engine] = e
+	}
+	poisson, fitted := byName["poisson"], byName["fitted"]
+	if poisson.BrierScore == fitted.BrierScore && poisson.LogLoss == fitted.LogLoss {
+		t.Errorf("the fitted engine scored exactly like the Poisson engine: %+v", fitted)
+	}
+}
+
+func TestFitBeforeSkipsTeamsOutsideTheSeason(t *testing.T) {
+	teams := rankingTeams(2)
+	played := []Match{playedMatch(1, 2, 2, 0), playedMatch(1, 9, 5, 0), playedMatch(9, 2, 0, 5)}
+
+	want := fitBefore(teams, played[:1], 2)
+	if got := fitBefore(teams, played, 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("matches against team 9 changed the fit: %+v, want %+v", got, want)
+	}
+}
@@ -2,52 +2,204 @@ package main
 
 import (
 	"math"
+	"math/rand"
+	"runtime"
+	"sync"
 )
 
-// SimulateChampionshipProbabilities simulates the championship probabilities for each team
-func SimulateChampionshipProbabilities(teamService TeamService, matchService MatchService, currentWeek int) (map[int]float64, error) {
-	const numSimulations = 15000
-	counts := make(map[int]int)
+// maxSimulationShards bounds how many independent random streams a Monte Carlo run is split into.
+// The shard count only depends on the number of simulations, never on the CPU count,
+// so the same seed gives the same result on every machine.
+const maxSimulationShards = 64
 
+// simFixture is a remaining match with both teams addressed by their index in the table
+type simFixture struct {
+	home, away int
+}
+
+// seasonSimulation holds everything a Monte Carlo run of the rest of the season needs
+type seasonSimulation struct {
+	table    []Team        // standings before the remaining fixtures
+	fixtures []simFixture  // remaining fixtures in play order
+	played   []matchResult // results already played, only kept when the tiebreakers need them
+	settings LeagueSettings
+	workers  int // 0 uses every CPU
+}
+
+// simulationObserver collects statistics from the final tables of the simulations of one shard
+type simulationObserver interface {
+	observe(table []Team)
+	merge(other simulationObserver)
+}
+
+// newSeasonSimulation prepares a simulation of every unplayed match of the season,
+// games in hand left behind in earlier weeks included
+func newSeasonSimulation(teams []Team, matches []Match, settings LeagueSettings) *seasonSimulation {
+	// Start every simulation from the table computed from this exact snapshot of matches
+	table := computeStandings(teams, matches, settings)
+	index := make(map[int]int, len(table))
+	for i, t := range table {
+		index[t.ID] = i
+	}
+
+	seasonLength := settings.seasonLength(matches)
+	var fixtures []simFixture
+	for _, m := range matches {
+		if m.Played || m.Week > seasonLength {
+			continue
+		}
+		home, homeOK := index[m.HomeTeamID]
+		away, awayOK := index[m.AwayTeamID]
+		if homeOK && awayOK {
+			fixtures = append(fixtures, simFixture{home: home, away: away})
+		}
+	}
+	simulation := &seasonSimulation{table: table, fixtures: fixtures, settings: settings}
+	if settings.needsResults() {
+		simulation.played = playedResults(matches)
+	}
+	return simulation
+}
+
+// run plays the remaining fixtures settings.Simulations times, sharded across a pool of workers.
+// Each shard has its own random source seeded from rng and its own observer, the observers are merged in shard order.
+func (s *seasonSimulation) run(rng *rand.Rand, newObserver func() simulationObserver) simulationObserver {
+	simulations := s.settings.Simulations
+	shards := min(simulations, maxSimulationShards)
+	seeds := make([]int64, shards)
+	for i := range seeds {
+		seeds[i] = rng.Int63()
+	}
+	observers := make([]simulationObserver, shards)
+
+	workers := s.workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, shards); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			work := make([]Team, len(s.table))
+			for shard := range jobs {
+				// Spread the remainder over the first shards
+				count := simulations / shards
+				if shard < simulations%shards {
+					count++
+				}
+				observers[shard] = s.runShard(newRNG(seeds[shard]), count, work, newObserver())
+			}
+		}()
+	}
+	for shard := 0; shard < shards; shard++ {
+		jobs <- shard
+	}
+	close(jobs)
+	wg.Wait()
+
+	result := newObserver()
+	for _, o := range observers {
+		result.merge(o)
+	}
+	return result
+}
+
+// runShard plays count seasons with one random source, reusing work as the table buffer
+func (s *seasonSimulation) runShard(rng *rand.Rand, count int, work []Team, observer simulationObserver) simulationObserver {
+	engine, points := s.settings.Engine, s.settings.Points
+	// Head-to-head tiebreakers need every result of the simulated season, not only the table
+	var results []matchResult
+	if s.played != nil {
+		results = make([]matchResult, len(s.played), len(s.played)+len(s.fixtures))
+		copy(results, s.played)
+	}
+	for sim := 0; sim < count; sim++ {
+		copy(work, s.table)
+		if results != nil {
+			results = results[:len(s.played)]
+		}
+		for _, f := range s.fixtures {
+			homeGoals, awayGoals := engine.SimulateMatch(rng, work[f.home], work[f.away])
+			applyResult(&work[f.home], &work[f.away], homeGoals, awayGoals, points)
+			if results != nil {
+				results = append(results, matchResult{s.table[f.home].ID, s.table[f.away].ID, homeGoals, awayGoals})
+			}
+		}
+		s.settings.rankTable(work, results, rng)
+		observer.observe(work)
+	}
+	return observer
+}
+
+// titleCounter counts how often each team finishes first
+type titleCounter struct {
+	counts map[int]int
+	total  int
+}
+
+// newTitleCounter creates an empty titleCounter
+func newTitleCounter() simulationObserver {
+	return &titleCounter{counts: make(map[int]int)}
+}
+
+// observe records the champion of one simulated season
+func (t *titleCounter) observe(table []Team) {
+	t.counts[table[0].ID]++
+	t.total++
+}
+
+// merge adds the counts of another shard
+func (t *titleCounter) merge(other simulationObserver) {
+	o := other.(*titleCounter)
+	for id, count := range o.counts {
+		t.counts[id] += count
+	}
+	t.total += o.total
+}
+
+// SimulateChampionshipProbabilities simulates the championship probabilities for each team.
+// All randomness comes from rng, so the same seed gives the same probabilities for the same data.
+func SimulateChampionshipProbabilities(teamService TeamService, matchService MatchService, settings LeagueSettings, rng *rand.Rand) (map[int]float64, error) {
 	// Get real teams and matches from the database
-	initialTeams, err := teamService.GetTeams()
+	teams, err := teamService.GetTeams()
 	if err != nil {
 		return nil, err
 	}
-	initialMatches, err := matchService.GetMatches()
+	matches, err := matchService.GetMatches()
 	if err != nil {
 		return nil, err
 	}
 
-	// Monte Carlo simulation to estimate championship probabilities
-	for sim := 0; sim < numSimulations; sim++ {
-		// Clone initial teams and matches to avoid modifying the original data
-		teams := cloneTeams(initialTeams)
-		matches := cloneMatches(initialMatches)
-
-		// Play remaining weeks
-		for week := currentWeek + 1; week <= 6; week++ {
-			playWeekSimulation(week, teams, matches)
-		}
+	return titleProbabilities(teams, matches, settings, rng), nil
+}
 
-		// Find the leader of the championship
-		leaderID := findLeader(teams)
-		counts[leaderID]++
+// titleProbabilities runs the Monte Carlo simulation of the rest of the season for a snapshot of teams and matches
+func titleProbabilities(teams []Team, matches []Match, settings LeagueSettings, rng *rand.Rand) map[int]float64 {
+	// Monte Carlo simulation to estimate championship probabilities
+	simulation := newSeasonSimulation(teams, matches, settings)
+	if len(simulation.table) == 0 {
+		return map[int]float64{}
 	}
+	counter := simulation.run(rng, newTitleCounter).(*titleCounter)
 
 	// Calculate probabilities based on counts
-	probabilities := map[int]float64{
-		// Initialize probabilities map
-		1: 0.0,
-		2: 0.0,
-		3: 0.0,
-		4: 0.0,
-	}
-	for teamID, count := range counts {
-		realValue := (float64(count) / float64(numSimulations)) * 100.0 // Convert to percentage without decreasing precision
-		probabilities[teamID] = math.Round(realValue*1000) / 1000.0 // Round to three decimal place
-	}
-	return probabilities, nil
+	probabilities := make(map[int]float64, len(simulation.table))
+	for _, t := range simulation.table {
+		// Initialize probabilities map so teams without any title are still listed
+		probabilities[t.ID] = 0.0
+	}
+	for teamID, count := range counter.counts {
+		probabilities[teamID] = percentage(count, counter.total)
+	}
+	return probabilities
+}
+
+// percentage converts a count to a percentage rounded to three decimal places
+func percentage(count, total int) float64 {
+	realValue := (float64(count) / float64(total)) * 100.0 // Convert to percentage without decreasing precision
+	return math.Round(realValue*1000) / 1000.0              // Round to three decimal place
 }
 
 // Clone teams from db to avoid modifying the original data
@@ -65,6 +217,13 @@ func cloneTeams(original []Team) []Team {
 			Draws:  		t.Draws,
 			Losses: 		t.Losses,
 			Points:  		t.Points,
+			FairPlayPoints: t.FairPlayPoints,
+			PointsDeducted: t.PointsDeducted,
+			DecidedBy: 		t.DecidedBy,
+			GamesInHand:    t.GamesInHand,
+			Rating:         t.Rating,
+			Seed:           t.Seed,
+			Group:          t.Group,
 		}
 	}
 	return cloned
@@ -84,78 +243,15 @@ func cloneMatches(original []Match) []Match {
 			AwayGoals:   m.AwayGoals,
 			Week:        m.Week,
 			Played:      m.Played,
+			SeasonID:    m.SeasonID,
+			Round:       m.Round,
+			Leg:         m.Leg,
+			Tie:         m.Tie,
+			ExtraTime:   m.ExtraTime,
+			HomePenalties: m.HomePenalties,
+			AwayPenalties: m.AwayPenalties,
+			Postponed:   m.Postponed,
 		}
 	}
 	return cloned
 }
-
-// This function simulates a week of matches, updates the scores, and returns the standings
-func playWeekSimulation(week int, teams []Team, matches []Match) () {
-	for i := range matches {
-		match := &matches[i]
-		if match.Week == week && !match.Played {
-			homeTeam := findTeamByID(teams, match.HomeTeamID)
-			awayTeam := findTeamByID(teams, match.AwayTeamID)
-
-			if homeTeam != nil && awayTeam != nil {
-				homeGoals, awayGoals := simulateMatch(homeTeam.Strength, awayTeam.Strength)
-
-				// Update match results
-				match.HomeGoals = &homeGoals
-				match.AwayGoals = &awayGoals
-				match.Played = true
-
-				// Update teams' stats
-				updateTeamStats(homeTeam, homeGoals, awayGoals)
-				updateTeamStats(awayTeam, awayGoals, homeGoals)
-			}
-		}
-	}
-}
-
-// Find a team by its ID in the list of teams
-func findTeamByID(teams []Team, id int) *Team {
-	for i := range teams {
-		if teams[i].ID == id {
-			return &teams[i]
-		}
-	}
-	return nil
-} 
-
-// Update the team statistics based on the match results
-func updateTeamStats(team *Team, goalsFor int, goalsAgainst int) {
-	team.GoalsFor += goalsFor
-	team.GoalsAgainst += goalsAgainst
-	team.GoalDiff = team.GoalsFor - team.GoalsAgainst
-
-	if goalsFor > goalsAgainst {
-		team.Wins++
-		team.Points += 3 // 3 points for a win
-	} else if goalsFor < goalsAgainst {
-		team.Losses++
-	} else {
-		team.Draws++
-		team.Points++ // 1 point for a draw
-	}
-}
-
-// Find the leader of the championship based on points
-func findLeader(teams []Team) int {
-    leaderID := -1
-    maxPoints := -1
-    maxGoalDiff := -1
-    maxGoalsFor := -1
-
-    for _, team := range teams {
-        if team.Points > maxPoints || // Check for higher points
-            (team.Points == maxPoints && team.GoalDiff > maxGoalDiff) || // Check for higher goal difference if points are equal
-            (team.Points == maxPoints && team.GoalDiff == maxGoalDiff && team.GoalsFor > maxGoalsFor) { // Check for higher goals for if points and goal difference are equal
-            maxPoints = team.Points
-            maxGoalDiff = team.GoalDiff
-            maxGoalsFor = team.GoalsFor
-            leaderID = team.ID
-        }
-    }
-    return leaderID
-}
\ No newline at end of file
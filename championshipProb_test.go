@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+// benchmarkLeague builds the sample league with its first half played, the point where probabilities start
+func benchmarkLeague(settings LeagueSettings) ([]Team, []Match, int) {
+	rng := newRNG(1)
+	teams := sampleTeams()
+	matches := generateRoundRobin(teams, true)
+	currentWeek := seasonLengthOf(matches) / 2
+
+	byID := teamsByID(teams)
+	for i := range matches {
+		m := &matches[i]
+		m.ID = i + 1
+		if m.Week <= currentWeek {
+			homeGoals, awayGoals := settings.Engine.SimulateMatch(rng, byID[m.HomeTeamID], byID[m.AwayTeamID])
+			m.HomeGoals, m.AwayGoals, m.Played = &homeGoals, &awayGoals, true
+		}
+	}
+	return computeStandings(teams, matches, settings), matches, currentWeek
+}
+
+// sequentialTitleCounts is the original single threaded simulation, kept as the baseline:
+// it clones every team and match per run and finds teams with a linear search
+func sequentialTitleCounts(rng *rand.Rand, teams []Team, matches []Match, currentWeek int, settings LeagueSettings) map[int]int {
+	counts := make(map[int]int)
+	seasonLength := settings.seasonLength(matches)
+	findTeamByID := func(teams []Team, id int) *Team {
+		for i := range teams {
+			if teams[i].ID == id {
+				return &teams[i]
+			}
+		}
+		return nil
+	}
+
+	for sim := 0; sim < settings.Simulations; sim++ {
+		simTeams := cloneTeams(teams)
+		simMatches := cloneMatches(matches)
+		for week := currentWeek + 1; week <= seasonLength; week++ {
+			for i := range simMatches {
+				match := &simMatches[i]
+				if match.Week != week || match.Played {
+					continue
+				}
+				homeTeam := findTeamByID(simTeams, match.HomeTeamID)
+				awayTeam := findTeamByID(simTeams, match.AwayTeamID)
+				homeGoals, awayGoals := settings.Engine.SimulateMatch(rng, *homeTeam, *awayTeam)
+				match.HomeGoals, match.AwayGoals, match.Played = &homeGoals, &awayGoals, true
+				applyResult(homeTeam, awayTeam, homeGoals, awayGoals, settings.Points)
+			}
+		}
+		settings.rankTable(simTeams, playedResults(simMatches), rng)
+		counts[simTeams[0].ID]++
+	}
+	return counts
+}
+
+// benchmarkSettings are the default settings of the server
+func benchmarkSettings() LeagueSettings {
+	cfg := defaultConfig()
+	return cfg.settings()
+}
+
+func BenchmarkChampionshipProbabilitiesSequential(b *testing.B) {
+	settings := benchmarkSettings()
+	teams, matches, currentWeek := benchmarkLeague(settings)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sequentialTitleCounts(newRNG(1), teams, matches, currentWeek, settings)
+	}
+	b.ReportMetric(float64(settings.Simulations)*float64(b.N)/b.Elapsed().Seconds(), "simulations/s")
+}
+
+func BenchmarkChampionshipProbabilitiesSharded(b *testing.B) {
+	settings := benchmarkSettings()
+	teams, matches, _ := benchmarkLeague(settings)
+	for _, workers := range slices.Compact([]int{1, runtime.GOMAXPROCS(0)}) {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			simulation := newSeasonSimulation(teams, matches, settings)
+			simulation.workers = workers
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				simulation.run(newRNG(1), newTitleCounter)
+			}
+			b.ReportMetric(float64(settings.Simulations)*float64(b.N)/b.Elapsed().Seconds(), "simulations/s")
+		})
+	}
+}
+
+// The sharded simulation must give the same title distribution as the sequential baseline, within Monte Carlo noise
+func TestShardedSimulationMatchesSequential(t *testing.T) {
+	settings := benchmarkSettings()
+	settings.Simulations = 40000
+	if testing.Short() {
+		settings.Simulations = 5000
+	}
+	teams, matches, currentWeek := benchmarkLeague(settings)
+
+	baseline := sequentialTitleCounts(newRNG(2), teams, matches, currentWeek, settings)
+	sharded := newSeasonSimulation(teams, matches, settings).run(newRNG(2), newTitleCounter).(*titleCounter)
+	if sharded.total != settings.Simulations {
+		t.Fatalf("sharded simulation ran %d seasons, want %d", sharded.total, settings.Simulations)
+	}
+	for _, team := range teams {
+		p := percentage(baseline[team.ID], settings.Simulations)
+		q := percentage(sharded.counts[team.ID], sharded.total)
+		// Five standard errors of the difference of two independent estimates
+		tolerance := 5 * 100 * math.Sqrt(2*(p/100)*(1-p/100)/float64(settings.Simulations))
+		if diff := math.Abs(p - q); diff > math.Max(tolerance, 0.5) {
+			t.Errorf("%s: sequential %.3f%%, sharded %.3f%%, difference %.3f above %.3f", team.Name, p, q, diff, tolerance)
+		}
+	}
+}
+
+// The same seed must give the same probabilities on every run, however the shards are spread over the workers
+func TestSimulationIsDeterministicForASeed(t *testing.T) {
+	settings := benchmarkSettings()
+	teams, matches, _ := benchmarkLeague(settings)
+
+	for _, simulations := range []int{1, 10, maxSimulationShards + 1, 5000} {
+		settings.Simulations = simulations
+		first := titleProbabilities(teams, matches, settings, newRNG(42))
+		if again := titleProbabilities(teams, matches, settings, newRNG(42)); !reflect.DeepEqual(first, again) {
+			t.Errorf("%d simulations: seed 42 gave %v and then %v", simulations, first, again)
+		}
+
+		var counts []map[int]int
+		for _, workers := range []int{1, 3, maxSimulationShards} {
+			simulation := newSeasonSimulation(teams, matches, settings)
+			simulation.workers = workers
+			counter := simulation.run(newRNG(42), newTitleCounter).(*titleCounter)
+			if counter.total != simulations {
+				t.Fatalf("%d simulations on %d workers ran %d seasons", simulations, workers, counter.total)
+			}
+			counts = append(counts, counter.counts)
+		}
+		for i := 1; i < len(counts); i++ {
+			if !reflect.DeepEqual(counts[0], counts[i]) {
+				t.Errorf("%d simulations: title counts differ between worker pools: %v and %v", simulations, counts[0], counts[i])
+			}
+		}
+	}
+
+	settings.Simulations = 5000
+	if first, other := titleProbabilities(teams, matches, settings, newRNG(42)), titleProbabilities(teams, matches, settings, newRNG(43)); reflect.DeepEqual(first, other) {
+		t.Errorf("seeds 42 and 43 gave the same probabilities %v", first)
+	}
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxClinchSearchNodes bounds each exhaustive search over the remaining outcomes.
+// The sample league needs a few hundred nodes, larger leagues early in the season can exceed it.
+const maxClinchSearchNodes = 2_000_000
+
+// TitleOutlook is the exact state of the title race for one team, looking at points only.
+// Teams level on points are assumed to lose the tiebreak for clinching and the worst position,
+// and to win it for the best position, since the remaining scores can still move goal difference.
+type TitleOutlook struct {
+	TeamID        int    `json:"team_id"`
+	Name          string `json:"name"`
+	Points        int    `json:"points"`
+	MaxPoints     int    `json:"max_points"`
+	Status        string `json:"status"`       // clinched, eliminated or alive
+	MagicNumber   *int   `json:"magic_number"` // points won by the team or dropped by its rivals that clinch the title, nil once eliminated
+	BestPosition  int    `json:"best_position"`
+	WorstPosition int    `json:"worst_position"`
+	Exact         bool   `json:"exact"` // false when a search ran out of nodes, or bonus points leave no single best result, and the positions are the best found so far
+}
+
+// TitleRace is the title analysis of every team in table order
+type TitleRace struct {
+	RemainingMatches int            `json:"remaining_matches"`
+	Teams            []TitleOutlook `json:"teams"`
+}
+
+// outcomeSearch explores the results of the remaining fixtures that do not involve one team
+type outcomeSearch struct {
+	fixtures  []simFixture
+	remaining [][]int  // remaining[k][j] is the most points team j can still win from fixtures[k:]
+	outcomes  [][2]int // every pair of home and away points a match can award
+	threshold int
+	nodes     int
+}
+
+// newOutcomeSearch prepares a search over fixtures for a table with the given points
+func newOutcomeSearch(fixtures []simFixture, teams int, outcomes [][2]int, threshold int) *outcomeSearch {
+	maxHome, maxAway := mostPoints(outcomes)
+	remaining := make([][]int, len(fixtures)+1)
+	remaining[len(fixtures)] = make([]int, teams)
+	for k := len(fixtures) - 1; k >= 0; k-- {
+		remaining[k] = append([]int(nil), remaining[k+1]...)
+		remaining[k][fixtures[k].home] += maxHome
+		remaining[k][fixtures[k].away] += maxAway
+	}
+	return &outcomeSearch{fixtures: fixtures, remaining: remaining, outcomes: outcomes, threshold: threshold}
+}
+
+// mostPoints returns the most points a single match can award to the home and to the away team
+func mostPoints(outcomes [][2]int) (int, int) {
+	maxHome, maxAway := 0, 0
+	for _, o := range outcomes {
+		maxHome, maxAway = max(maxHome, o[0]), max(maxAway, o[1])
+	}
+	return maxHome, maxAway
+}
+
+// extremeOutcome picks the outcome with the most (best) or fewest (worst) points for one side of the match,
+// breaking ties towards the fewest (best) or most (worst) points for the other side.
+// dominant reports whether that outcome is at least as good (or bad) for the side as every other on both counts.
+func extremeOutcome(outcomes [][2]int, side int, best bool) (pick [2]int, dominant bool) {
+	other := 1 - side
+	better := func(a, b [2]int) bool {
+		if best {
+			return a[side] > b[side] || a[side] == b[side] && a[other] < b[other]
+		}
+		return a[side] < b[side] || a[side] == b[side] && a[other] > b[other]
+	}
+	pick = outcomes[0]
+	for _, o := range outcomes[1:] {
+		if better(o, pick) {
+			pick = o
+		}
+	}
+	dominant = true
+	for _, o := range outcomes {
+		if best && (o[side] > pick[side] || o[other] < pick[other]) || !best && (o[side] < pick[side] || o[other] > pick[other]) {
+			dominant = false
+		}
+	}
+	return pick, dominant
+}
+
+// fewestAbove finds the smallest possible number of teams finishing strictly above the threshold
+func (s *outcomeSearch) fewestAbove(table []int) (int, bool) {
+	// Teams that cannot pass the threshold never count, so their number is where the search starts from
+	best := 0
+	for j, p := range table {
+		if p+s.remaining[0][j] > s.threshold {
+			best++
+		}
+	}
+	var dfs func(k int) bool
+	dfs = func(k int) bool {
+		s.nodes++
+		if s.nodes > maxClinchSearchNodes {
+			return false
+		}
+		// Points never go down, so teams already above stay above
+		above := 0
+		for _, p := range table {
+			if p > s.threshold {
+				above++
+			}
+		}
+		if above >= best {
+			return true
+		}
+		if k == len(s.fixtures) {
+			best = above
+			return true
+		}
+		f := s.fixtures[k]
+		for _, o := range s.outcomes {
+			table[f.home] += o[0]
+			table[f.away] += o[1]
+			ok := dfs(k + 1)
+			table[f.home] -= o[0]
+			table[f.away] -= o[1]
+			if !ok {
+				return false
+			}
+		}
+		return true
+	}
+	exact := dfs(0)
+	return best, exact
+}
+
+// mostAtOrAbove finds the largest possible number of teams finishing level with or above the threshold
+func (s *outcomeSearch) mostAtOrAbove(table []int) (int, bool) {
+	best := 0
+	for _, p := range table {
+		if p >= s.threshold {
+			best++
+		}
+	}
+	var dfs func(k int) bool
+	dfs = func(k int) bool {
+		s.nodes++
+		if s.nodes > maxClinchSearchNodes {
+			return false
+		}
+		// Count the teams that can still reach the threshold with the fixtures left
+		reachable, reached := 0, 0
+		for j, p := range table {
+			if p >= s.threshold {
+				reached++
+			}
+			if p+s.remaining[k][j] >= s.threshold {
+				reachable++
+			}
+		}
+		if reachable <= best {
+			return true
+		}
+		if k == len(s.fixtures) {
+			best = reached
+			return true
+		}
+		f := s.fixtures[k]
+		for _, o := range s.outcomes {
+			table[f.home] += o[0]
+			table[f.away] += o[1]
+			ok := dfs(k + 1)
+			table[f.home] -= o[0]
+			table[f.away] -= o[1]
+			if !ok {
+				return false
+			}
+		}
+		return true
+	}
+	exact := dfs(0)
+	return best, exact
+}
+
+// analyzeTitleRace computes the exact title outlook of every team from the table and the remaining fixtures.
+// The table is in ranked order, so without fixtures left it is final and every team keeps its place.
+func analyzeTitleRace(table []Team, fixtures []simFixture, points PointsSystem) []TitleOutlook {
+	if len(fixtures) == 0 {
+		return finalTitleRace(table)
+	}
+	outcomes := points.outcomes()
+	maxHome, maxAway := mostPoints(outcomes)
+	bestHome, bestHomeDominant := extremeOutcome(outcomes, 0, true)
+	bestAway, bestAwayDominant := extremeOutcome(outcomes, 1, true)
+	worstHome, worstHomeDominant := extremeOutcome(outcomes, 0, false)
+	worstAway, worstAwayDominant := extremeOutcome(outcomes, 1, false)
+	bestDominant := bestHomeDominant && bestAwayDominant
+	worstDominant := worstHomeDominant && worstAwayDominant
+
+	maxPoints := make([]int, len(table))
+	for i, t := range table {
+		maxPoints[i] = t.Points
+	}
+	for _, f := range fixtures {
+		maxPoints[f.home] += maxHome
+		maxPoints[f.away] += maxAway
+	}
+
+	outlooks := make([]TitleOutlook, len(table))
+	for i, t := range table {
+		// The fixtures of the team itself are decided up front: its best result in each is its best case,
+		// its worst result its worst case. The search only covers the other fixtures.
+		var others []simFixture
+		best := make([]int, len(table))
+		worst := make([]int, len(table))
+		for j, u := range table {
+			best[j], worst[j] = u.Points, u.Points
+		}
+		for _, f := range fixtures {
+			switch i {
+			case f.home:
+				best[f.home] += bestHome[0]
+				best[f.away] += bestHome[1]
+				worst[f.home] += worstHome[0]
+				worst[f.away] += worstHome[1]
+			case f.away:
+				best[f.home] += bestAway[0]
+				best[f.away] += bestAway[1]
+				worst[f.home] += worstAway[0]
+				worst[f.away] += worstAway[1]
+			default:
+				others = append(others, f)
+			}
+		}
+		// The team itself never counts against its own threshold, which can be negative after deductions
+		ceiling, guaranteed := best[i], worst[i]
+		best[i], worst[i] = math.MinInt/2, math.MinInt/2
+
+		above, bestExact := newOutcomeSearch(others, len(table), outcomes, ceiling).fewestAbove(best)
+		level, worstExact := newOutcomeSearch(others, len(table), outcomes, guaranteed).mostAtOrAbove(worst)
+		bestExact = bestExact && bestDominant
+		worstExact = worstExact && worstDominant
+
+		// The closest rival is the one with the highest ceiling, every point it drops or the team wins
+		// beyond its guaranteed points counts towards the title
+		rivalMax := guaranteed - 1
+		for j := range table {
+			if j != i {
+				rivalMax = max(rivalMax, maxPoints[j])
+			}
+		}
+		magic := max(rivalMax-guaranteed+1, 0)
+
+		outlook := TitleOutlook{
+			TeamID:        t.ID,
+			Name:          t.Name,
+			Points:        t.Points,
+			MaxPoints:     maxPoints[i],
+			Status:        "alive",
+			MagicNumber:   &magic,
+			BestPosition:  above + 1,
+			WorstPosition: level + 1,
+			Exact:         bestExact && worstExact,
+		}
+		if magic == 0 || level == 0 && worstExact {
+			outlook.Status = "clinched"
+			magic = 0
+		} else if above > 0 && bestExact {
+			outlook.Status = "eliminated"
+			outlook.MagicNumber = nil
+		}
+		outlooks[i] = outlook
+	}
+	return outlooks
+}
+
+// finalTitleRace is the outcome of a finished season: the leader of the ranked table has won the title
+func finalTitleRace(table []Team) []TitleOutlook {
+	outlooks := make([]TitleOutlook, len(table))
+	for i, t := range table {
+		outlooks[i] = TitleOutlook{
+			TeamID:        t.ID,
+			Name:          t.Name,
+			Points:        t.Points,
+			MaxPoints:     t.Points,
+			Status:        "eliminated",
+			BestPosition:  i + 1,
+			WorstPosition: i + 1,
+			Exact:         true,
+		}
+		if i == 0 {
+			magic := 0
+			outlooks[i].Status, outlooks[i].MagicNumber = "clinched", &magic
+		}
+	}
+	return outlooks
+}
+
+// AnalyzeTitleRace reports which teams have clinched or are eliminated from the title given the unplayed matches
+func AnalyzeTitleRace(teamService TeamService, matchService MatchService, settings LeagueSettings) (*TitleRace, error) {
+	teams, err := teamService.GetTeams()
+	if err != nil {
+		return nil, err
+	}
+	matches, err := matchService.GetMatches()
+	if err != nil {
+		return nil, err
+	}
+
+	// The simulation's table is ranked with the configured tiebreakers, which settle the positions of a finished season
+	simulation := newSeasonSimulation(teams, matches, settings)
+	return &TitleRace{
+		RemainingMatches: len(simulation.fixtures),
+		Teams:            analyzeTitleRace(simulation.table, simulation.fixtures, settings.Points),
+	}, nil
+}
+
+// requestTitleRace reads the optional ?title_race= query parameter asking /play-week for the title race as well
+func requestTitleRace(c *gin.Context) (bool, error) {
+	value, ok := c.GetQuery("title_race")
+	if !ok || value == "" {
+		return false, nil
+	}
+	include, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("title_race must be true or false, got %q", value)
+	}
+	return include, nil
+}
+
+// TitleRaceHandler handles the request for the exact title race of the season.
+// The searches can take seconds in large leagues early in the season, so the analysis has its own endpoint.
+func TitleRaceHandler(teamService TeamService, matchService MatchService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		titleRace, err := AnalyzeTitleRace(teamService, matchService, matchService.Settings())
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, titleRace)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// titleTable builds a table of teams 1..n with the given points
+func titleTable(points ...int) []Team {
+	table := make([]Team, len(points))
+	for i, p := range points {
+		table[i] = Team{ID: i + 1, Name: string(rune('A' + i)), Points: p}
+	}
+	return table
+}
+
+// wantOutlook is a hand-worked outlook, magic -1 stands for no magic number
+type wantOutlook struct {
+	status      string
+	magic       int
+	maxPoints   int
+	best, worst int
+}
+
+func checkOutlooks(t *testing.T, got []TitleOutlook, want []wantOutlook) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d outlooks, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		g := got[i]
+		magic := -1
+		if g.MagicNumber != nil {
+			magic = *g.MagicNumber
+		}
+		if g.Status != w.status || magic != w.magic || g.MaxPoints != w.maxPoints || g.BestPosition != w.best || g.WorstPosition != w.worst || !g.Exact {
+			t.Errorf("team %s: got status=%s magic=%d max=%d positions %d-%d exact=%v, want status=%s magic=%d max=%d positions %d-%d exact",
+				g.Name, g.Status, magic, g.MaxPoints, g.BestPosition, g.WorstPosition, g.Exact, w.status, w.magic, w.maxPoints, w.best, w.worst)
+		}
+	}
+}
+
+func TestTitleRaceClinchedAndEliminated(t *testing.T) {
+	// A on 10 is out of reach of B, the only one who can get to 9, so the last round only decides the places behind A.
+	// B takes 9 by beating A or stays on 6 where C can catch it, D can draw level with C on 5 at best.
+	table := titleTable(10, 6, 5, 2)
+	fixtures := []simFixture{{0, 1}, {2, 3}}
+	checkOutlooks(t, analyzeTitleRace(table, fixtures, defaultPoints), []wantOutlook{
+		{"clinched", 0, 13, 1, 1},
+		{"eliminated", -1, 9, 2, 3},
+		{"eliminated", -1, 8, 2, 4},
+		{"eliminated", -1, 5, 3, 4},
+	})
+}
+
+func TestTitleRaceMagicNumbers(t *testing.T) {
+	// Everyone plays everyone once more. A is guaranteed 9 and B can reach 13, so A needs 5 points won or dropped by B.
+	// C can still win it: beating A and B gives C 10 while a draw between A and B leaves them on 10 and 8.
+	table := titleTable(9, 7, 4)
+	fixtures := []simFixture{{0, 1}, {0, 2}, {1, 2}}
+	checkOutlooks(t, analyzeTitleRace(table, fixtures, defaultPoints), []wantOutlook{
+		{"alive", 5, 15, 1, 3},
+		{"alive", 9, 13, 1, 3},
+		{"alive", 12, 10, 1, 3},
+	})
+}
+
+func TestTitleRaceSeasonOver(t *testing.T) {
+	// Without fixtures the ranked table is final, the tiebreakers have put A ahead of B on the same points
+	table := titleTable(7, 7, 3)
+	checkOutlooks(t, analyzeTitleRace(table, nil, defaultPoints), []wantOutlook{
+		{"clinched", 0, 7, 1, 1},
+		{"eliminated", -1, 7, 2, 2},
+		{"eliminated", -1, 3, 3, 3},
+	})
+}
+
+func TestFinishedSeasonTitleRaceFollowsTheTable(t *testing.T) {
+	leagueService := &MyLeagueService{store: newMemoryStore(sampleTeams()), settings: benchmarkSettings()}
+	services, err := leagueService.SeasonServices(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := newRNG(1)
+	for {
+		if _, _, err := services.matches.PlayWeek(rng); err != nil {
+			break
+		}
+	}
+
+	race, err := AnalyzeTitleRace(services.teams, services.matches, services.matches.Settings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, _ := services.teams.GetTeams()
+	if race.RemainingMatches != 0 || len(race.Teams) != len(table) {
+		t.Fatalf("title race of a finished season: %+v", race)
+	}
+	for i, outlook := range race.Teams {
+		if outlook.TeamID != table[i].ID || outlook.BestPosition != i+1 || outlook.WorstPosition != i+1 || !outlook.Exact {
+			t.Errorf("%s: positions %d-%d exact=%v, finished %d", outlook.Name, outlook.BestPosition, outlook.WorstPosition, outlook.Exact, i+1)
+		}
+		if want := map[bool]string{true: "clinched", false: "eliminated"}[i == 0]; outlook.Status != want {
+			t.Errorf("%s finished %d and is %s, want %s", outlook.Name, i+1, outlook.Status, want)
+		}
+	}
+}
+
+func TestTitleRaceMagicNumberFollowsResults(t *testing.T) {
+	// A win for A over B takes 3 off A's magic number and 3 off B's ceiling, and clinches the title
+	before := analyzeTitleRace(titleTable(9, 7, 4), []simFixture{{0, 1}, {0, 2}, {1, 2}}, defaultPoints)
+	after := analyzeTitleRace(titleTable(12, 7, 4), []simFixture{{0, 2}, {1, 2}}, defaultPoints)
+	if *before[0].MagicNumber != 5 || *after[0].MagicNumber != 0 || after[0].Status != "clinched" {
+		t.Errorf("magic number of A went from %d to %d (%s), want 5 to 0 and clinched",
+			*before[0].MagicNumber, *after[0].MagicNumber, after[0].Status)
+	}
+}
+
+func TestPlayWeekReportsTitleRaceOnRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, tt := range []struct {
+		query string
+		want  bool
+		code  int
+	}{
+		{"", false, http.StatusOK},
+		{"&title_race=true", true, http.StatusOK},
+		{"&title_race=maybe", false, http.StatusBadRequest},
+	} {
+		leagueService := &MyLeagueService{store: newMemoryStore(sampleTeams()), settings: benchmarkSettings()}
+		services, err := leagueService.SeasonServices(1, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		router := gin.New()
+		router.POST("/play-week", PlayWeekHandler(services.teams, services.matches))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/play-week?seed=1&simulations=10"+tt.query, nil))
+		if w.Code != tt.code {
+			t.Fatalf("%q: status %d, want %d: %s", tt.query, w.Code, tt.code, w.Body.String())
+		}
+		if got := strings.Contains(w.Body.String(), `"title_race"`); got != tt.want {
+			t.Errorf("%q: title_race in response = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
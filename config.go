@@ -0,0 +1,264 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds every setting of the server.
+// Values are read from the defaults, then the optional config file, then LEAGUE_* environment variables.
+type Config struct {
+	Database         DatabaseConfig `yaml:"database" toml:"database"`
+	ListenAddr       string         `yaml:"listen_addr" toml:"listen_addr"`
+	Simulations      int            `yaml:"simulations" toml:"simulations"`
+	SeasonLength     int            `yaml:"season_length" toml:"season_length"` // 0 derives the length from the schedule
+	Points           PointsSystem   `yaml:"points" toml:"points"`
+	MatchEngine      string         `yaml:"match_engine" toml:"match_engine"`           // legacy, poisson, dixon-coles or fitted, for leagues choosing none
+	Tiebreakers      []string       `yaml:"tiebreakers" toml:"tiebreakers"`             // applied in order to teams level on points
+	GroupTiebreakers []string       `yaml:"group_tiebreakers" toml:"group_tiebreakers"` // the same for the groups of a tournament
+	Ratings          RatingsConfig  `yaml:"ratings" toml:"ratings"`
+	LogLevel         string         `yaml:"log_level" toml:"log_level"`
+}
+
+// DatabaseConfig selects the storage backend
+type DatabaseConfig struct {
+	Driver string `yaml:"driver" toml:"driver"` // mysql, sqlite or memory
+	DSN    string `yaml:"dsn" toml:"dsn"`       // MySQL DSN or SQLite file path
+}
+
+// RatingsConfig configures the Elo ratings, in rating points
+type RatingsConfig struct {
+	KFactor       int `yaml:"k_factor" toml:"k_factor"` // 0 plays with the static strengths
+	HomeAdvantage int `yaml:"home_advantage" toml:"home_advantage"`
+}
+
+// LeagueSettings holds the configurable parameters the services simulate with
+type LeagueSettings struct {
+	Simulations      int
+	SeasonLength     int // 0 derives the length from the schedule
+	Points           PointsSystem
+	Engine           MatchEngine   // the configured engine, replaced by the league's own in the settings of its seasons
+	Fitted           *fittedEngine // shared by every league playing with the fitted engine
+	Tiebreakers      []Tiebreaker
+	GroupTiebreakers []Tiebreaker
+	Ratings          RatingSettings
+}
+
+// seasonLength returns the number of weeks to play, capped by the configured season length
+func (s LeagueSettings) seasonLength(matches []Match) int {
+	length := seasonLengthOf(matches)
+	if s.SeasonLength > 0 && s.SeasonLength < length {
+		return s.SeasonLength
+	}
+	return length
+}
+
+// engineNamed returns the engine a league plays with, the configured one when the league names none.
+// Every league choosing the fitted engine plays with the one instance holding the stored model.
+func (s LeagueSettings) engineNamed(name string) (MatchEngine, error) {
+	switch name {
+	case "", s.Engine.Name():
+		return s.Engine, nil
+	case "fitted":
+		return s.Fitted, nil
+	}
+	return matchEngineByName(name)
+}
+
+// forLeague returns the settings the seasons of a league are played with
+func (s LeagueSettings) forLeague(l League) LeagueSettings {
+	if engine, err := s.engineNamed(l.Engine); err == nil {
+		s.Engine = engine
+	}
+	return s
+}
+
+// defaultConfig returns the settings the server has always used
+func defaultConfig() Config {
+	return Config{
+		Database:    DatabaseConfig{Driver: "mysql"},
+		ListenAddr:  ":8080",
+		Simulations: 15000,
+		Points:      defaultPoints,
+		MatchEngine: "legacy",
+		Tiebreakers: []string{string(tiebreakGoalDifference), string(tiebreakGoalsFor)}, // the Premier League rules
+		GroupTiebreakers: []string{ // the UEFA group stage rules
+			string(tiebreakH2HPoints), string(tiebreakH2HGoalDiff), string(tiebreakH2HGoalsFor),
+			string(tiebreakGoalDifference), string(tiebreakGoalsFor),
+		},
+		Ratings:  RatingsConfig{KFactor: 20, HomeAdvantage: 60},
+		LogLevel: "info",
+	}
+}
+
+// loadConfig builds the configuration from the defaults, the config file (if any) and the environment
+func loadConfig(path string) (Config, error) {
+	cfg := defaultConfig()
+
+	if path == "" {
+		path = os.Getenv("LEAGUE_CONFIG")
+	}
+	if path != "" {
+		if err := cfg.readFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+	if err := cfg.readEnv(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// readFile overlays the values of a YAML or TOML file, chosen by its extension
+func (c *Config) readFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read config file: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, c)
+	case ".toml":
+		err = toml.Unmarshal(content, c)
+	default:
+		return fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// readEnv overlays every LEAGUE_* environment variable that is set
+func (c *Config) readEnv() error {
+	textVars := map[string]*string{
+		"LEAGUE_DB_DRIVER":    &c.Database.Driver,
+		"LEAGUE_DB_DSN":       &c.Database.DSN,
+		"LEAGUE_LISTEN_ADDR":  &c.ListenAddr,
+		"LEAGUE_MATCH_ENGINE": &c.MatchEngine,
+		"LEAGUE_LOG_LEVEL":    &c.LogLevel,
+	}
+	for name, field := range textVars {
+		if value, ok := os.LookupEnv(name); ok {
+			*field = value
+		}
+	}
+
+	if value, ok := os.LookupEnv("LEAGUE_TIEBREAKERS"); ok {
+		c.Tiebreakers = strings.Split(value, ",")
+	}
+	if value, ok := os.LookupEnv("LEAGUE_GROUP_TIEBREAKERS"); ok {
+		c.GroupTiebreakers = strings.Split(value, ",")
+	}
+
+	numberVars := map[string]*int{
+		"LEAGUE_SIMULATIONS":            &c.Simulations,
+		"LEAGUE_SEASON_LENGTH":          &c.SeasonLength,
+		"LEAGUE_POINTS_WIN":             &c.Points.Win,
+		"LEAGUE_POINTS_DRAW":            &c.Points.Draw,
+		"LEAGUE_POINTS_LOSS":            &c.Points.Loss,
+		"LEAGUE_RATINGS_K_FACTOR":       &c.Ratings.KFactor,
+		"LEAGUE_RATINGS_HOME_ADVANTAGE": &c.Ratings.HomeAdvantage,
+	}
+	for name, field := range numberVars {
+		if value, ok := os.LookupEnv(name); ok {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("%s must be a number, got %q", name, value)
+			}
+			*field = n
+		}
+	}
+	return nil
+}
+
+// validate checks every setting and fills in the SQLite file default, a MySQL DSN holds credentials and must be configured
+func (c *Config) validate() error {
+	var errs []error
+
+	switch c.Database.Driver {
+	case "mysql":
+		if c.Database.DSN == "" {
+			errs = append(errs, errors.New("database dsn is required for mysql, set database.dsn, LEAGUE_DB_DSN or -dsn, or use -storage sqlite"))
+		}
+	case "sqlite":
+		if c.Database.DSN == "" {
+			c.Database.DSN = "league.db"
+		}
+	case "memory":
+	default:
+		errs = append(errs, fmt.Errorf("database driver must be mysql, sqlite or memory, got %q", c.Database.Driver))
+	}
+	if c.ListenAddr == "" {
+		errs = append(errs, errors.New("listen address must not be empty"))
+	}
+	if c.Simulations < 1 || c.Simulations > maxRequestSimulations {
+		errs = append(errs, fmt.Errorf("simulations must be between 1 and %d, got %d", maxRequestSimulations, c.Simulations))
+	}
+	if c.SeasonLength < 0 {
+		errs = append(errs, fmt.Errorf("season length must not be negative, got %d", c.SeasonLength))
+	}
+	if err := c.Points.validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := matchEngineByName(c.MatchEngine); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := parseTiebreakers(c.Tiebreakers); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := parseTiebreakers(c.GroupTiebreakers); err != nil {
+		errs = append(errs, fmt.Errorf("group %w", err))
+	}
+	if c.Ratings.KFactor < 0 || c.Ratings.KFactor > 100 {
+		errs = append(errs, fmt.Errorf("ratings k factor must be between 0 and 100, got %d", c.Ratings.KFactor))
+	}
+	if c.Ratings.HomeAdvantage < 0 || c.Ratings.HomeAdvantage > 400 {
+		errs = append(errs, fmt.Errorf("ratings home advantage must be between 0 and 400, got %d", c.Ratings.HomeAdvantage))
+	}
+	if _, err := c.logLevel(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// logLevel parses the configured log level
+func (c *Config) logLevel() (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return 0, fmt.Errorf("log level must be debug, info, warn or error, got %q", c.LogLevel)
+	}
+	return level, nil
+}
+
+// settings returns the parts of the configuration used by the services, the config must be valid
+func (c *Config) settings() LeagueSettings {
+	fitted := &fittedEngine{}
+	engine, _ := matchEngineByName(c.MatchEngine)
+	if c.MatchEngine == fitted.Name() {
+		engine = fitted
+	}
+	tiebreakers, _ := parseTiebreakers(c.Tiebreakers)
+	groupTiebreakers, _ := parseTiebreakers(c.GroupTiebreakers)
+	return LeagueSettings{
+		Simulations:      c.Simulations,
+		SeasonLength:     c.SeasonLength,
+		Points:           c.Points,
+		Engine:           engine,
+		Fitted:           fitted,
+		Tiebreakers:      tiebreakers,
+		GroupTiebreakers: groupTiebreakers,
+		Ratings: RatingSettings{
+			KFactor:       float64(c.Ratings.KFactor),
+			HomeAdvantage: float64(c.Ratings.HomeAdvantage),
+		},
+	}
+}
@@ -0,0 +1,515 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CupEntrant is a team in the cup bracket with its seed, 1 being the strongest
+type CupEntrant struct {
+	TeamID int    `json:"team_id"`
+	Name   string `json:"name"`
+	Seed   int    `json:"seed"`
+}
+
+// CupTie is one pairing of the bracket, played over one or two legs
+type CupTie struct {
+	Round    int         `json:"round"`
+	Tie      int         `json:"tie"`
+	Home     *CupEntrant `json:"home"` // hosts the first or only leg, nil until both teams are known
+	Away     *CupEntrant `json:"away"`
+	Bye      bool        `json:"bye,omitempty"` // the home team goes through without playing
+	Matches  []Match     `json:"matches"`
+	WinnerID *int        `json:"winner_id"`
+}
+
+// CupRound is one round of the bracket, the final is always a single match
+type CupRound struct {
+	Round int      `json:"round"`
+	Name  string   `json:"name"`
+	Legs  int      `json:"legs"`
+	Ties  []CupTie `json:"ties"`
+}
+
+// CupBracket is the whole knockout bracket derived from the seeds and the matches played so far
+type CupBracket struct {
+	Rounds     []CupRound `json:"rounds"`
+	ChampionID *int       `json:"champion_id"`
+}
+
+// CupOdds is the chance of a team to reach every stage of the cup, in percent
+type CupOdds struct {
+	TeamID int       `json:"team_id"`
+	Name   string    `json:"name"`
+	Seed   int       `json:"seed"`
+	Reach  []float64 `json:"reach"` // aligned with CupProbabilities.Stages
+}
+
+// CupProbabilities lists the stages of the cup and every team's odds of reaching them
+type CupProbabilities struct {
+	Stages      []string  `json:"stages"`
+	Teams       []CupOdds `json:"teams"`
+	Simulations int       `json:"simulations"`
+}
+
+// CupService interface defines methods for playing a season in the knockout cup format
+type CupService interface {
+	Bracket() (CupBracket, error)
+	PlayRound(rng *rand.Rand) (int, CupBracket, error)
+	Probabilities(rng *rand.Rand, simulations int) (CupProbabilities, error)
+}
+
+// MyCupService implements CupService interface
+type MyCupService struct {
+	repo     LeagueRepository
+	settings LeagueSettings
+	legs     int
+}
+
+// Bracket returns the bracket with every result played so far
+func (s *MyCupService) Bracket() (CupBracket, error) {
+	teams, err := s.repo.Teams()
+	if err != nil {
+		return CupBracket{}, err
+	}
+	matches, err := s.repo.Matches()
+	if err != nil {
+		return CupBracket{}, err
+	}
+	return buildCupBracket(teams, matches, s.legs), nil
+}
+
+// PlayRound plays every remaining leg of the earliest unfinished round and draws the ties of the next one.
+// It returns the round that was played and the updated bracket.
+func (s *MyCupService) PlayRound(rng *rand.Rand) (int, CupBracket, error) {
+	var round int
+	var bracket CupBracket
+	err := s.repo.Atomic(func(repo LeagueRepository) error {
+		teams, err := repo.Teams()
+		if err != nil {
+			return err
+		}
+		matches, err := repo.Matches()
+		if err != nil {
+			return err
+		}
+
+		var played []Match
+		round, played = playCupRound(buildCupBracket(teams, matches, s.legs), teamsByID(teams), s.settings.Engine, rng)
+		if round == 0 {
+			return fmt.Errorf("%w: the cup has been decided", errInvalidInput)
+		}
+		for _, m := range played {
+			if err := repo.SaveKnockoutResult(m); err != nil {
+				return err
+			}
+		}
+
+		matches, err = repo.Matches()
+		if err != nil {
+			return err
+		}
+		next, err := repo.AddMatches(pendingCupMatches(buildCupBracket(teams, matches, s.legs), s.legs, 1))
+		if err != nil {
+			return err
+		}
+		bracket = buildCupBracket(teams, append(matches, next...), s.legs)
+
+		// Keep the stored counters in line with the results, like every other write
+		_, err = syncStandings(repo, s.settings)
+		return err
+	})
+	if err != nil {
+		return 0, CupBracket{}, err
+	}
+	return round, bracket, nil
+}
+
+// Probabilities plays the rest of the cup many times and counts how often each team reaches each stage
+func (s *MyCupService) Probabilities(rng *rand.Rand, simulations int) (CupProbabilities, error) {
+	teams, err := s.repo.Teams()
+	if err != nil {
+		return CupProbabilities{}, err
+	}
+	matches, err := s.repo.Matches()
+	if err != nil {
+		return CupProbabilities{}, err
+	}
+	if simulations <= 0 {
+		simulations = s.settings.Simulations
+	}
+
+	byID := teamsByID(teams)
+	base := buildCupBracket(teams, matches, s.legs)
+	stages := len(base.Rounds) + 1
+	counts := make(map[int][]int, len(teams))
+	for _, t := range teams {
+		counts[t.ID] = make([]int, stages)
+	}
+
+	for i := 0; i < simulations; i++ {
+		bracket := playOutCup(teams, byID, matches, s.legs, 1, s.settings.Engine, rng)
+		countCupStages(bracket, counts)
+	}
+
+	result := CupProbabilities{Simulations: simulations}
+	for _, round := range base.Rounds {
+		result.Stages = append(result.Stages, round.Name)
+	}
+	result.Stages = append(result.Stages, "Winner")
+	for _, e := range cupEntrants(teams) {
+		odds := CupOdds{TeamID: e.TeamID, Name: e.Name, Seed: e.Seed, Reach: make([]float64, stages)}
+		for stage, count := range counts[e.TeamID] {
+			odds.Reach[stage] = percentage(count, simulations)
+		}
+		result.Teams = append(result.Teams, odds)
+	}
+	slices.SortStableFunc(result.Teams, func(a, b CupOdds) int {
+		return cmp.Compare(b.Reach[stages-1], a.Reach[stages-1])
+	})
+	return result, nil
+}
+
+// playOutCup simulates the rest of a bracket, drawing every later round as its teams become known.
+// The drawn matches get negative ids so that they never clash with the stored ones.
+func playOutCup(teams []Team, byID map[int]Team, matches []Match, legs, firstWeek int, engine MatchEngine, rng *rand.Rand) CupBracket {
+	simulated := cloneMatches(matches)
+	nextID := -1
+	bracket := buildCupBracket(teams, simulated, legs)
+	for bracket.ChampionID == nil {
+		for _, m := range pendingCupMatches(bracket, legs, firstWeek) {
+			m.ID = nextID
+			nextID--
+			simulated = append(simulated, m)
+		}
+		bracket = buildCupBracket(teams, simulated, legs)
+		round, played := playCupRound(bracket, byID, engine, rng)
+		if round == 0 {
+			// A level tie without a shootout, only possible after results were edited by hand
+			break
+		}
+		for _, m := range played {
+			j := slices.IndexFunc(simulated, func(other Match) bool { return other.ID == m.ID })
+			simulated[j] = m
+		}
+		bracket = buildCupBracket(teams, simulated, legs)
+	}
+	return bracket
+}
+
+// countCupStages adds one to the count of every stage each team reached in a played out bracket,
+// the last stage being the title
+func countCupStages(bracket CupBracket, counts map[int][]int) {
+	for _, round := range bracket.Rounds {
+		for _, tie := range round.Ties {
+			for _, e := range []*CupEntrant{tie.Home, tie.Away} {
+				if e != nil {
+					counts[e.TeamID][round.Round-1]++
+				}
+			}
+		}
+	}
+	if bracket.ChampionID != nil {
+		counts[*bracket.ChampionID][len(bracket.Rounds)]++
+	}
+}
+
+// teamsByID indexes teams by their id
+func teamsByID(teams []Team) map[int]Team {
+	byID := make(map[int]Team, len(teams))
+	for _, t := range teams {
+		byID[t.ID] = t
+	}
+	return byID
+}
+
+// cupEntrants orders the teams by seed, renumbering the seeds from 1 without gaps
+func cupEntrants(teams []Team) []CupEntrant {
+	seeded := slices.Clone(teams)
+	slices.SortStableFunc(seeded, func(a, b Team) int {
+		return cmp.Or(cmp.Compare(a.Seed, b.Seed), cmp.Compare(a.ID, b.ID))
+	})
+	entrants := make([]CupEntrant, len(seeded))
+	for i, t := range seeded {
+		entrants[i] = CupEntrant{TeamID: t.ID, Name: t.Name, Seed: i + 1}
+	}
+	return entrants
+}
+
+// bracketOrder returns the seed at every position of a bracket of the given size,
+// placed so that the top seeds can only meet in the late rounds and the byes go to the top seeds
+func bracketOrder(size int) []int {
+	order := []int{1}
+	for n := 2; n <= size; n *= 2 {
+		next := make([]int, 0, n)
+		for _, seed := range order {
+			next = append(next, seed, n+1-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+// cupRoundName names a round after the number of teams left in it
+func cupRoundName(round, rounds int) string {
+	switch rounds - round {
+	case 0:
+		return "Final"
+	case 1:
+		return "Semi-finals"
+	case 2:
+		return "Quarter-finals"
+	}
+	return fmt.Sprintf("Round of %d", 2<<(rounds-round))
+}
+
+// buildCupBracket places the seeded teams in a bracket padded with byes to a power of two,
+// and fills in every tie from the stored matches
+func buildCupBracket(teams []Team, matches []Match, legs int) CupBracket {
+	entrants := cupEntrants(teams)
+	size, rounds := 1, 0
+	for size < len(entrants) {
+		size *= 2
+		rounds++
+	}
+	byID := make(map[int]*CupEntrant, len(entrants))
+	for i := range entrants {
+		byID[entrants[i].TeamID] = &entrants[i]
+	}
+
+	tieMatches := make(map[[2]int][]Match)
+	for _, m := range matches {
+		if m.Round > 0 {
+			key := [2]int{m.Round, m.Tie}
+			tieMatches[key] = append(tieMatches[key], m)
+		}
+	}
+
+	order := bracketOrder(size)
+	bracket := CupBracket{Rounds: make([]CupRound, rounds)}
+	for r := 1; r <= rounds; r++ {
+		round := CupRound{Round: r, Name: cupRoundName(r, rounds), Legs: legs}
+		if r == rounds {
+			round.Legs = 1
+		}
+		for k := 1; k <= size>>r; k++ {
+			var a, b *CupEntrant
+			if r == 1 {
+				if seed := order[2*k-2]; seed <= len(entrants) {
+					a = &entrants[seed-1]
+				}
+				if seed := order[2*k-1]; seed <= len(entrants) {
+					b = &entrants[seed-1]
+				}
+			} else {
+				previous := bracket.Rounds[r-2].Ties
+				if id := previous[2*k-2].WinnerID; id != nil {
+					a = byID[*id]
+				}
+				if id := previous[2*k-1].WinnerID; id != nil {
+					b = byID[*id]
+				}
+			}
+
+			tie := CupTie{Round: r, Tie: k, Matches: tieMatches[[2]int{r, k}]}
+			slices.SortFunc(tie.Matches, func(x, y Match) int { return cmp.Compare(x.Leg, y.Leg) })
+			if tie.Matches == nil {
+				tie.Matches = []Match{}
+			}
+			switch {
+			case r == 1 && (a == nil || b == nil):
+				tie.Home, tie.Bye = cmp.Or(a, b), true
+				tie.WinnerID = &tie.Home.TeamID
+			case a != nil && b != nil:
+				if a.Seed > b.Seed {
+					a, b = b, a
+				}
+				// The better seed hosts a single match, or the second leg of a two-legged tie
+				tie.Home, tie.Away = a, b
+				if round.Legs == 2 {
+					tie.Home, tie.Away = b, a
+				}
+				tie.WinnerID = cupTieWinner(tie, round.Legs)
+			}
+			round.Ties = append(round.Ties, tie)
+		}
+		bracket.Rounds[r-1] = round
+	}
+	if rounds > 0 {
+		bracket.ChampionID = bracket.Rounds[rounds-1].Ties[0].WinnerID
+	}
+	return bracket
+}
+
+// cupTieWinner decides a tie on aggregate goals, extra time included, then on penalties in the last leg.
+// It returns nil while a leg is still to be played.
+func cupTieWinner(tie CupTie, legs int) *int {
+	if len(tie.Matches) < legs {
+		return nil
+	}
+	goals := make(map[int]int, 2)
+	for _, m := range tie.Matches {
+		if !m.Played || m.HomeGoals == nil || m.AwayGoals == nil {
+			return nil
+		}
+		goals[m.HomeTeamID] += *m.HomeGoals
+		goals[m.AwayTeamID] += *m.AwayGoals
+	}
+	home, away := tie.Home.TeamID, tie.Away.TeamID
+	switch {
+	case goals[home] > goals[away]:
+		return &home
+	case goals[away] > goals[home]:
+		return &away
+	}
+	last := tie.Matches[len(tie.Matches)-1]
+	if last.HomePenalties == nil || last.AwayPenalties == nil || *last.HomePenalties == *last.AwayPenalties {
+		return nil
+	}
+	if *last.HomePenalties > *last.AwayPenalties {
+		return &last.HomeTeamID
+	}
+	return &last.AwayTeamID
+}
+
+// pendingCupMatches creates the legs of every tie whose teams are known but that has no matches yet.
+// Round r leg l is played in week firstWeek + (r-1)*legs + l-1.
+func pendingCupMatches(bracket CupBracket, legs, firstWeek int) []Match {
+	var matches []Match
+	for _, round := range bracket.Rounds {
+		for _, tie := range round.Ties {
+			if tie.Bye || tie.Home == nil || tie.Away == nil || len(tie.Matches) > 0 {
+				continue
+			}
+			for leg := 1; leg <= round.Legs; leg++ {
+				home, away := tie.Home, tie.Away
+				if leg == 2 {
+					home, away = away, home
+				}
+				matches = append(matches, Match{
+					NameHome:   home.Name,
+					NameAway:   away.Name,
+					HomeTeamID: home.TeamID,
+					AwayTeamID: away.TeamID,
+					Week:       firstWeek + (round.Round-1)*legs + leg - 1,
+					Round:      round.Round,
+					Leg:        leg,
+					Tie:        tie.Tie,
+				})
+			}
+		}
+	}
+	return matches
+}
+
+// playCupRound simulates every unplayed leg of the earliest round that has any.
+// A tie still level after its last leg goes to extra time and then to penalties.
+// It returns the round played, 0 when nothing is left, and the played matches.
+func playCupRound(bracket CupBracket, teams map[int]Team, engine MatchEngine, rng *rand.Rand) (int, []Match) {
+	for _, round := range bracket.Rounds {
+		var played []Match
+		for _, tie := range round.Ties {
+			for i, m := range tie.Matches {
+				if m.Played {
+					continue
+				}
+				home, away := teams[m.HomeTeamID], teams[m.AwayTeamID]
+				homeGoals, awayGoals := engine.SimulateMatch(rng, home, away)
+
+				if m.Leg == round.Legs {
+					// Goals of the earlier legs, seen from this match's sides
+					homeTotal, awayTotal := homeGoals, awayGoals
+					for _, earlier := range tie.Matches[:i] {
+						if earlier.HomeTeamID == m.HomeTeamID {
+							homeTotal, awayTotal = homeTotal+*earlier.HomeGoals, awayTotal+*earlier.AwayGoals
+						} else {
+							homeTotal, awayTotal = homeTotal+*earlier.AwayGoals, awayTotal+*earlier.HomeGoals
+						}
+					}
+					if homeTotal == awayTotal {
+						extraHome, extraAway := simulateExtraTime(rng, engine, home, away)
+						homeGoals, awayGoals = homeGoals+extraHome, awayGoals+extraAway
+						m.ExtraTime = true
+						if extraHome == extraAway {
+							homePenalties, awayPenalties := simulateShootout(rng, home, away)
+							m.HomePenalties, m.AwayPenalties = &homePenalties, &awayPenalties
+						}
+					}
+				}
+
+				m.HomeGoals, m.AwayGoals, m.Played = &homeGoals, &awayGoals, true
+				tie.Matches[i] = m
+				played = append(played, m)
+			}
+		}
+		if len(played) > 0 {
+			return round.Round, played
+		}
+	}
+	return 0, nil
+}
+
+// CupBracketHandler handles the request for the cup bracket
+func CupBracketHandler(cupService CupService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bracket, err := cupService.Bracket()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, bracket)
+	}
+}
+
+// PlayCupRoundHandler handles the request to play the next round of the cup
+func PlayCupRoundHandler(cupService CupService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		seed, err := requestSeed(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		round, bracket, err := cupService.PlayRound(newRNG(seed))
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message": fmt.Sprintf("%s played successfully", bracket.Rounds[round-1].Name),
+			"bracket": bracket,
+			"seed":    seed,
+		})
+	}
+}
+
+// CupProbabilitiesHandler handles the request for every team's odds of reaching each round of the cup
+func CupProbabilitiesHandler(cupService CupService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		seed, err := requestSeed(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		simulations, err := requestSimulations(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		probabilities, err := cupService.Probabilities(newRNG(seed), simulations)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"probabilities": probabilities,
+			"seed":          seed,
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// cupTeams returns teams 1..n seeded in id order
+func cupTeams(n int) []Team {
+	teams := groupTeams(n)
+	for i := range teams {
+		teams[i].Seed = i + 1
+	}
+	return teams
+}
+
+// cupMatch returns a played leg of a cup tie
+func cupMatch(round, tie, leg, home, away, homeGoals, awayGoals int) Match {
+	return Match{Round: round, Tie: tie, Leg: leg, HomeTeamID: home, AwayTeamID: away, HomeGoals: &homeGoals, AwayGoals: &awayGoals, Played: true}
+}
+
+func TestBracketOrderKeepsTopSeedsApart(t *testing.T) {
+	if got := bracketOrder(8); !slices.Equal(got, []int{1, 8, 4, 5, 2, 7, 3, 6}) {
+		t.Errorf("bracket of 8: %v", got)
+	}
+	for size := 2; size <= 64; size *= 2 {
+		order := bracketOrder(size)
+		// Round 1 pairs seed s with seed size+1-s, and seeds 1 and 2 sit in different halves
+		for k := 0; k < size; k += 2 {
+			if order[k]+order[k+1] != size+1 {
+				t.Errorf("size %d: seeds %d and %d meet in round 1", size, order[k], order[k+1])
+			}
+		}
+		if i := slices.Index(order, 2); i < size/2 {
+			t.Errorf("size %d: seed 2 is in the half of seed 1", size)
+		}
+	}
+}
+
+func TestCupBracketGivesByesToTopSeeds(t *testing.T) {
+	bracket := buildCupBracket(cupTeams(6), nil, 1)
+
+	var names []string
+	for _, round := range bracket.Rounds {
+		names = append(names, round.Name)
+	}
+	if !slices.Equal(names, []string{"Quarter-finals", "Semi-finals", "Final"}) {
+		t.Fatalf("rounds %v", names)
+	}
+
+	var byes []int
+	var pairs [][2]int
+	for _, tie := range bracket.Rounds[0].Ties {
+		if tie.Bye {
+			if tie.Away != nil || tie.WinnerID == nil || *tie.WinnerID != tie.Home.TeamID {
+				t.Errorf("bye of seed %d does not send it through: %+v", tie.Home.Seed, tie)
+			}
+			byes = append(byes, tie.Home.Seed)
+			continue
+		}
+		pairs = append(pairs, [2]int{tie.Home.Seed, tie.Away.Seed})
+	}
+	if !slices.Equal(byes, []int{1, 2}) || !slices.Equal(pairs, [][2]int{{4, 5}, {3, 6}}) {
+		t.Errorf("byes %v and ties %v, want byes [1 2] and ties [[4 5] [3 6]]", byes, pairs)
+	}
+
+	// The teams with a bye wait in the next round for the winners of the ties beside them
+	semi := bracket.Rounds[1].Ties
+	if semi[0].Home != nil || semi[1].Home != nil || len(pendingCupMatches(bracket, 1, 1)) != 2 {
+		t.Errorf("semi-finals drawn before the first round is played: %+v", semi)
+	}
+}
+
+func TestCupTieWinnerOnAggregateThenPenalties(t *testing.T) {
+	home, away := &CupEntrant{TeamID: 2, Seed: 2}, &CupEntrant{TeamID: 1, Seed: 1}
+	penalties := func(m Match, homePenalties, awayPenalties int) Match {
+		m.ExtraTime, m.HomePenalties, m.AwayPenalties = true, &homePenalties, &awayPenalties
+		return m
+	}
+	tests := []struct {
+		name    string
+		matches []Match
+		want    int // 0 while undecided
+	}{
+		{"second leg to play", []Match{cupMatch(1, 1, 1, 2, 1, 1, 0)}, 0},
+		{"aggregate", []Match{cupMatch(1, 1, 1, 2, 1, 1, 0), cupMatch(1, 1, 2, 1, 2, 3, 1)}, 1},
+		// 2-2 on aggregate, away goals do not count
+		{"level without a shootout", []Match{cupMatch(1, 1, 1, 2, 1, 2, 1), cupMatch(1, 1, 2, 1, 2, 1, 0)}, 0},
+		{"penalties", []Match{cupMatch(1, 1, 1, 2, 1, 2, 1), penalties(cupMatch(1, 1, 2, 1, 2, 1, 0), 3, 4)}, 2},
+	}
+	for _, tt := range tests {
+		got := cupTieWinner(CupTie{Home: home, Away: away, Matches: tt.matches}, 2)
+		switch {
+		case tt.want == 0 && got != nil:
+			t.Errorf("%s: won by %d, want undecided", tt.name, *got)
+		case tt.want != 0 && (got == nil || *got != tt.want):
+			t.Errorf("%s: won by %v, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPlayCupRoundSettlesDrawsWithExtraTimeAndPenalties(t *testing.T) {
+	for _, legs := range []int{1, 2} {
+		teams := cupTeams(5)
+		bracket := playOutCup(teams, teamsByID(teams), nil, legs, 1, goallessEngine{}, newRNG(1))
+		if bracket.ChampionID == nil {
+			t.Fatalf("%d legs: goalless cup has no champion", legs)
+		}
+		for _, round := range bracket.Rounds {
+			for _, tie := range round.Ties {
+				if tie.Bye {
+					continue
+				}
+				if len(tie.Matches) != round.Legs || tie.WinnerID == nil {
+					t.Fatalf("%d legs: %s tie %d has %d matches and winner %v", legs, round.Name, tie.Tie, len(tie.Matches), tie.WinnerID)
+				}
+				// Only the last leg goes to extra time and penalties
+				for _, m := range tie.Matches {
+					last := m.Leg == round.Legs
+					if m.ExtraTime != last || (m.HomePenalties != nil) != last {
+						t.Errorf("%d legs: %s leg %d extra time %v penalties %v", legs, round.Name, m.Leg, m.ExtraTime, m.HomePenalties != nil)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// deductionDateLayout is the format of the date a deduction was imposed on
+const deductionDateLayout = "2006-01-02"
+
+// Deduction is an administrative points deduction, subtracted from the team's points in every table
+type Deduction struct {
+	ID       int    `json:"id"`
+	SeasonID int    `json:"season_id"`
+	TeamID   int    `json:"team_id"`
+	Points   int    `json:"points"`
+	Reason   string `json:"reason"`
+	Date     string `json:"date"`
+}
+
+// ListDeductionsHandler handles the request for the points deductions ledger
+func ListDeductionsHandler(teamService TeamService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		deductions, err := teamService.Deductions()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"deductions": deductions})
+	}
+}
+
+// AddDeductionHandler handles the request to deduct points from a team
+func AddDeductionHandler(teamService TeamService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req Deduction
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+		req.ID = 0
+		req.Reason = strings.TrimSpace(req.Reason)
+		if req.Points < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "points must be a positive number of points to deduct"})
+			return
+		}
+		if req.Reason == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "reason must not be empty"})
+			return
+		}
+		// The date defaults to today
+		if req.Date == "" {
+			req.Date = time.Now().Format(deductionDateLayout)
+		} else if _, err := time.Parse(deductionDateLayout, req.Date); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date must look like 2006-01-02"})
+			return
+		}
+
+		deduction, teams, err := teamService.DeductPoints(req)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{
+			"deduction": deduction,
+			"standings": teams,
+		})
+	}
+}
+
+// DeleteDeductionHandler handles the request to remove a deduction from the ledger
+func DeleteDeductionHandler(teamService TeamService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deduction id"})
+			return
+		}
+		teams, err := teamService.RemoveDeduction(id)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message":   "Deduction removed successfully",
+			"standings": teams,
+		})
+	}
+}
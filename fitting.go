@@ -0,0 +1,421 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limits of the iterative fit, the parameters move by less than fitTolerance long before fitMaxIterations
+const (
+	fitMaxIterations = 1000
+	fitTolerance     = 1e-9
+)
+
+// fitPrior is the half goal scored and conceded against an average team every team starts with,
+// it keeps a team that never scored or never conceded at a finite parameter
+const fitPrior = 0.5
+
+// FittedModel holds the Poisson parameters the fitted engine plays with.
+// The home side expects BaseGoals * HomeAdvantage * attack / defence of the away side, the away side BaseGoals * attack / defence.
+type FittedModel struct {
+	HomeAdvantage float64                  `json:"home_advantage"`
+	BaseGoals     float64                  `json:"base_goals"` // goals of an average team away at an average team
+	Teams         map[int]FittedParameters `json:"-"`
+}
+
+// FittedParameters are the attack and defence of one team relative to the average team, higher is better for both
+type FittedParameters struct {
+	Attack  float64 `json:"attack"`
+	Defence float64 `json:"defence"`
+}
+
+// FittedTeam is one team of a fit with the strength it suggests
+type FittedTeam struct {
+	TeamID   int    `json:"team_id,omitempty"` // 0 for a team of an uploaded file that does not exist
+	Name     string `json:"name"`
+	Matches  int    `json:"matches"`
+	Strength int    `json:"strength"` // static strength with the same ratio of expected goals as the fitted parameters
+	FittedParameters
+}
+
+// FitResult is the outcome of fitting the model to a set of played matches
+type FitResult struct {
+	Matches       int          `json:"matches"`
+	Iterations    int          `json:"iterations"`
+	LogLikelihood float64      `json:"log_likelihood"`
+	HomeAdvantage float64      `json:"home_advantage"`
+	BaseGoals     float64      `json:"base_goals"`
+	Teams         []FittedTeam `json:"teams"`
+	Applied       string       `json:"applied,omitempty"`
+}
+
+// FitSource selects the played matches to fit: stored seasons, every stored season by default, or results sent with the request
+type FitSource struct {
+	SeasonIDs []int            `json:"season_ids"`
+	Results   []ImportedResult `json:"matches"`
+}
+
+// ImportedResult is a played match read from a file, teams are matched to the stored ones by name ignoring case
+type ImportedResult struct {
+	HomeTeam  string `json:"home_team"`
+	AwayTeam  string `json:"away_team"`
+	HomeGoals int    `json:"home_goals"`
+	AwayGoals int    `json:"away_goals"`
+}
+
+// fitMatch is a played match with both teams addressed by their index in the fit
+type fitMatch struct {
+	home, away           int
+	homeGoals, awayGoals int
+}
+
+// Targets a fit can be applied to
+const (
+	applyStrength = "strength"
+	applyEngine   = "engine"
+)
+
+// StrengthFitService interface defines methods for estimating team strengths from played matches
+type StrengthFitService interface {
+	Fit(source FitSource, apply string) (FitResult, error)
+}
+
+// MyStrengthFitService implements StrengthFitService interface
+type MyStrengthFitService struct {
+	store  LeagueStore
+	engine *fittedEngine // played by every league choosing the fitted engine
+}
+
+// Fit estimates the parameters from the selected matches and optionally applies them
+// to the static strengths of the teams or to the fitted engine
+func (s *MyStrengthFitService) Fit(source FitSource, apply string) (FitResult, error) {
+	var result FitResult
+	var model FittedModel
+	err := s.store.Atomic(func(store LeagueStore) error {
+		teams, matches, err := fitData(store, source)
+		if err != nil {
+			return err
+		}
+		result, model = fitStrengths(teams, matches)
+
+		switch apply {
+		case applyStrength:
+			for _, t := range result.Teams {
+				if t.TeamID == 0 {
+					continue
+				}
+				team := teams[slices.IndexFunc(teams, func(team Team) bool { return team.ID == t.TeamID })]
+				team.Strength = t.Strength
+				if err := store.SaveTeam(team); err != nil {
+					return err
+				}
+			}
+		case applyEngine:
+			if err := store.SaveFittedModel(model); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return FitResult{}, err
+	}
+	if apply == applyEngine {
+		s.engine.use(model)
+	}
+	result.Applied = apply
+	return result, nil
+}
+
+// fitData collects the teams and played matches of a fit. Stored matches decided in extra time are left out,
+// their goals were not scored in 90 minutes.
+func fitData(store LeagueStore, source FitSource) ([]Team, []fitMatch, error) {
+	allTeams, err := store.AllTeams()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var teams []Team
+	var matches []fitMatch
+	index := make(map[string]int)
+	teamIndex := func(t Team) int {
+		key := strings.ToLower(t.Name)
+		if i, ok := index[key]; ok {
+			return i
+		}
+		index[key] = len(teams)
+		teams = append(teams, t)
+		return len(teams) - 1
+	}
+
+	if len(source.Results) > 0 {
+		if len(source.SeasonIDs) > 0 {
+			return nil, nil, fmt.Errorf("%w: send either season_ids or matches", errInvalidInput)
+		}
+		// Names that are not stored still take part in the fit, they only cannot be applied
+		byName := make(map[string]Team, len(allTeams))
+		for _, t := range allTeams {
+			byName[strings.ToLower(t.Name)] = t
+		}
+		lookup := func(name string) Team {
+			name = strings.TrimSpace(name)
+			if t, ok := byName[strings.ToLower(name)]; ok {
+				return t
+			}
+			return Team{Name: name}
+		}
+		for i, r := range source.Results {
+			if strings.TrimSpace(r.HomeTeam) == "" || strings.TrimSpace(r.AwayTeam) == "" || r.HomeGoals < 0 || r.AwayGoals < 0 {
+				return nil, nil, fmt.Errorf("%w: match %d needs both team names and non-negative goals", errInvalidInput, i+1)
+			}
+			home, away := teamIndex(lookup(r.HomeTeam)), teamIndex(lookup(r.AwayTeam))
+			if home == away {
+				return nil, nil, fmt.Errorf("%w: match %d has the same team on both sides", errInvalidInput, i+1)
+			}
+			matches = append(matches, fitMatch{home, away, r.HomeGoals, r.AwayGoals})
+		}
+	} else {
+		seasonIDs := source.SeasonIDs
+		if len(seasonIDs) == 0 {
+			leagues, err := store.Leagues()
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, l := range leagues {
+				seasons, err := store.Seasons(l.ID)
+				if err != nil {
+					return nil, nil, err
+				}
+				for _, season := range seasons {
+					seasonIDs = append(seasonIDs, season.ID)
+				}
+			}
+		}
+
+		byID := teamsByID(allTeams)
+		for _, id := range seasonIDs {
+			if _, err := store.Season(id); err != nil {
+				return nil, nil, fmt.Errorf("%w: %d", err, id)
+			}
+			seasonMatches, err := store.ForSeason(id).Matches()
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, m := range seasonMatches {
+				if !m.Played || m.ExtraTime || m.HomeGoals == nil || m.AwayGoals == nil {
+					continue
+				}
+				matches = append(matches, fitMatch{teamIndex(byID[m.HomeTeamID]), teamIndex(byID[m.AwayTeamID]), *m.HomeGoals, *m.AwayGoals})
+			}
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil, nil, fmt.Errorf("%w: there are no played matches to fit", errInvalidInput)
+	}
+	return teams, matches, nil
+}
+
+// fitStrengths estimates the attack and defence of every team and the home advantage by maximum likelihood
+// of independent Poisson scores (the Maher model), updating each parameter in turn to its closed form optimum
+// given the others until none of them moves. The prior adds half a goal against an average team to every team.
+func fitStrengths(teams []Team, matches []fitMatch) (FitResult, FittedModel) {
+	n := len(teams)
+	// The home side expects homeAdv * attack[home] * concede[away], the away side attack[away] * concede[home]
+	attack, concede := make([]float64, n), make([]float64, n)
+	scored, conceded, played := make([]float64, n), make([]float64, n), make([]int, n)
+	homeGoals := 0.0
+	for i := range attack {
+		attack[i], concede[i] = 1, 1
+	}
+	for _, m := range matches {
+		scored[m.home] += float64(m.homeGoals)
+		scored[m.away] += float64(m.awayGoals)
+		conceded[m.home] += float64(m.awayGoals)
+		conceded[m.away] += float64(m.homeGoals)
+		played[m.home]++
+		played[m.away]++
+		homeGoals += float64(m.homeGoals)
+	}
+
+	homeAdv := 1.0
+	iterations := 0
+	for iterations < fitMaxIterations {
+		iterations++
+		previous := slices.Concat(attack, concede, []float64{homeAdv})
+
+		exposure := make([]float64, n)
+		for _, m := range matches {
+			exposure[m.home] += homeAdv * concede[m.away]
+			exposure[m.away] += concede[m.home]
+		}
+		for i := range attack {
+			attack[i] = (scored[i] + fitPrior) / (exposure[i] + fitPrior)
+		}
+
+		clear(exposure)
+		for _, m := range matches {
+			exposure[m.away] += homeAdv * attack[m.home]
+			exposure[m.home] += attack[m.away]
+		}
+		for i := range concede {
+			concede[i] = (conceded[i] + fitPrior) / (exposure[i] + fitPrior)
+		}
+
+		expectedHome := 0.0
+		for _, m := range matches {
+			expectedHome += attack[m.home] * concede[m.away]
+		}
+		if homeGoals > 0 {
+			homeAdv = homeGoals / expectedHome
+		}
+
+		// Only attack * concede enters the likelihood, an average team concedes at rate 1
+		scale := geometricMean(concede)
+		for i := range concede {
+			concede[i] /= scale
+			attack[i] *= scale
+		}
+
+		change := 0.0
+		for i, v := range slices.Concat(attack, concede, []float64{homeAdv}) {
+			change = math.Max(change, math.Abs(v-previous[i])/previous[i])
+		}
+		if change < fitTolerance {
+			break
+		}
+	}
+
+	logLikelihood := 0.0
+	for _, m := range matches {
+		logLikelihood += poissonLogPMF(m.homeGoals, homeAdv*attack[m.home]*concede[m.away])
+		logLikelihood += poissonLogPMF(m.awayGoals, attack[m.away]*concede[m.home])
+	}
+
+	// Report every parameter relative to the average team
+	base := geometricMean(attack)
+	power := make([]float64, n)
+	for i := range teams {
+		power[i] = attack[i] / base / concede[i]
+	}
+	powerScale := geometricMean(power)
+
+	model := FittedModel{HomeAdvantage: homeAdv, BaseGoals: base, Teams: make(map[int]FittedParameters)}
+	result := FitResult{
+		Matches:       len(matches),
+		Iterations:    iterations,
+		LogLikelihood: math.Round(logLikelihood*1000) / 1000,
+		HomeAdvantage: math.Round(homeAdv*1000) / 1000,
+		BaseGoals:     math.Round(base*1000) / 1000,
+	}
+	for i, t := range teams {
+		params := FittedParameters{Attack: attack[i] / base, Defence: 1 / concede[i]}
+		if t.ID != 0 {
+			model.Teams[t.ID] = params
+		}
+		// The ratio of two strengths is the ratio of the goals the two teams expect against each other
+		strength := int(math.Round(baseStrength * power[i] / powerScale))
+		result.Teams = append(result.Teams, FittedTeam{
+			TeamID:   t.ID,
+			Name:     t.Name,
+			Matches:  played[i],
+			Strength: min(max(strength, minStrength), maxStrength),
+			FittedParameters: FittedParameters{
+				Attack:  math.Round(params.Attack*1000) / 1000,
+				Defence: math.Round(params.Defence*1000) / 1000,
+			},
+		})
+	}
+	slices.SortStableFunc(result.Teams, func(a, b FittedTeam) int { return cmp.Compare(b.Strength, a.Strength) })
+	return result, model
+}
+
+// geometricMean returns the geometric mean of positive values
+func geometricMean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += math.Log(v)
+	}
+	return math.Exp(sum / float64(len(values)))
+}
+
+// poissonLogPMF returns log P(X = k) for a Poisson distribution with mean lambda
+func poissonLogPMF(k int, lambda float64) float64 {
+	logFactorial, _ := math.Lgamma(float64(k + 1))
+	return float64(k)*math.Log(lambda) - lambda - logFactorial
+}
+
+// fittedEngine draws independent Poisson scores from the fitted attack, defence and home advantage.
+// Teams without fitted parameters are played by the Poisson engine with their strength or rating.
+type fittedEngine struct {
+	model atomic.Pointer[FittedModel]
+}
+
+// use replaces the parameters the engine plays with, simulations already running keep the old ones
+func (e *fittedEngine) use(model FittedModel) {
+	e.model.Store(&model)
+}
+
+// Name identifies the engine in the configuration
+func (*fittedEngine) Name() string { return "fitted" }
+
+// ExpectedGoals applies the fitted model when both teams have parameters
+func (e *fittedEngine) ExpectedGoals(home, away Team) (float64, float64) {
+	if model := e.model.Load(); model != nil {
+		h, homeOK := model.Teams[home.ID]
+		a, awayOK := model.Teams[away.ID]
+		if homeOK && awayOK {
+			return model.BaseGoals * model.HomeAdvantage * h.Attack / a.Defence, model.BaseGoals * a.Attack / h.Defence
+		}
+	}
+	return poissonEngine{}.ExpectedGoals(home, away)
+}
+
+// SimulateMatch samples each side independently
+func (e *fittedEngine) SimulateMatch(rng *rand.Rand, home, away Team) (int, int) {
+	lambda, mu := e.ExpectedGoals(home, away)
+	return samplePoisson(rng, lambda), samplePoisson(rng, mu)
+}
+
+// ScoreProbabilities is the product of the two Poisson distributions
+func (e *fittedEngine) ScoreProbabilities(home, away Team) [][]float64 {
+	lambda, mu := e.ExpectedGoals(home, away)
+	return outerProduct(poissonDistribution(lambda), poissonDistribution(mu))
+}
+
+// FitStrengthsHandler handles the request to fit team strengths to played matches.
+// Without apply it only previews the fit, apply=strength overwrites the static strengths and apply=engine the fitted engine's parameters.
+func FitStrengthsHandler(fitService StrengthFitService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		type FitStrengthsRequest struct {
+			FitSource
+			Apply string `json:"apply"`
+		}
+		var req FitStrengthsRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+		}
+		if req.Apply != "" && req.Apply != applyStrength && req.Apply != applyEngine {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "apply must be strength or engine"})
+			return
+		}
+
+		result, err := fitService.Fit(req.FitSource, req.Apply)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
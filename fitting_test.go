@@ -0,0 +1,82 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestFitRecoversKnownParameters(t *testing.T) {
+	// Attacks and defences have a geometric mean of 1, as the fit reports them
+	truth := FittedModel{HomeAdvantage: 1.3, BaseGoals: 1.2, Teams: map[int]FittedParameters{
+		1: {Attack: 1.6, Defence: 1.25},
+		2: {Attack: 1.25, Defence: 1.6},
+		3: {Attack: 1, Defence: 1},
+		4: {Attack: 1, Defence: 0.8},
+		5: {Attack: 0.8, Defence: 1},
+		6: {Attack: 0.625, Defence: 0.625},
+	}}
+	teams := groupTeams(len(truth.Teams))
+	engine := &fittedEngine{}
+	engine.use(truth)
+
+	// Every pair meets 50 times at each ground
+	rng := newRNG(1)
+	var matches []fitMatch
+	for range 50 {
+		for h := range teams {
+			for a := range teams {
+				if h == a {
+					continue
+				}
+				homeGoals, awayGoals := engine.SimulateMatch(rng, teams[h], teams[a])
+				matches = append(matches, fitMatch{h, a, homeGoals, awayGoals})
+			}
+		}
+	}
+
+	result, model := fitStrengths(teams, matches)
+	if result.Matches != len(matches) || result.Iterations >= fitMaxIterations {
+		t.Errorf("fit of %d matches took %d iterations", result.Matches, result.Iterations)
+	}
+	near := func(name string, got, want float64) {
+		t.Helper()
+		if math.Abs(got-want) > 0.1*want {
+			t.Errorf("%s %.3f, want %.3f", name, got, want)
+		}
+	}
+	near("home advantage", model.HomeAdvantage, truth.HomeAdvantage)
+	near("base goals", model.BaseGoals, truth.BaseGoals)
+	for id, want := range truth.Teams {
+		got := model.Teams[id]
+		near(teams[id-1].Name+" attack", got.Attack, want.Attack)
+		near(teams[id-1].Name+" defence", got.Defence, want.Defence)
+	}
+
+	// Teams 1 and 2 are equally strong overall, ahead of the three middling sides and team 6
+	var order []int
+	for _, team := range result.Teams {
+		order = append(order, team.TeamID)
+	}
+	if !slices.Contains(order[:2], 1) || !slices.Contains(order[:2], 2) || order[5] != 6 {
+		t.Errorf("teams ranked by suggested strength %v, want 1 and 2 first and 6 last", order)
+	}
+}
+
+func TestFitKeepsTeamsWithoutGoalsFinite(t *testing.T) {
+	teams := groupTeams(2)
+	// Team 1 never concedes and team 2 never scores
+	matches := []fitMatch{{0, 1, 2, 0}, {1, 0, 0, 1}, {0, 1, 0, 0}}
+
+	_, model := fitStrengths(teams, matches)
+	for id, params := range model.Teams {
+		for _, v := range []float64{params.Attack, params.Defence} {
+			if v <= 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+				t.Errorf("team %d fitted to %+v", id, params)
+			}
+		}
+	}
+	if first, second := model.Teams[1], model.Teams[2]; first.Attack <= second.Attack || first.Defence <= second.Defence {
+		t.Errorf("team 1 %+v is not ahead of team 2 %+v", first, second)
+	}
+}
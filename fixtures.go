@@ -0,0 +1,130 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// byeTeamID marks the empty slot added to an odd number of teams
+const byeTeamID = -1
+
+// generateRoundRobin builds a round-robin schedule for the given teams using the circle method.
+// With an odd number of teams one team sits out (bye) each week.
+// A double round-robin mirrors the first half with home and away swapped, so no team plays more than two home
+// or away games in a row.
+func generateRoundRobin(teams []Team, doubleRoundRobin bool) []Match {
+	if len(teams) < 2 {
+		return nil
+	}
+
+	names := make(map[int]string, len(teams))
+	ids := make([]int, 0, len(teams)+1)
+	// The bye takes the fixed slot so that the rotating teams stay balanced between home and away
+	if len(teams)%2 == 1 {
+		ids = append(ids, byeTeamID)
+	}
+	for _, t := range teams {
+		names[t.ID] = t.Name
+		ids = append(ids, t.ID)
+	}
+	n := len(ids)
+	rounds := n - 1
+
+	var matches []Match
+	for round := 0; round < rounds; round++ {
+		for i := 0; i < n/2; i++ {
+			home, away := ids[i], ids[n-1-i]
+			// Alternate home/away so that no team plays more than two home or away games in a row
+			if i == 0 {
+				if round%2 == 1 {
+					home, away = away, home
+				}
+			} else if i%2 == 1 {
+				home, away = away, home
+			}
+			if home == byeTeamID || away == byeTeamID {
+				continue
+			}
+			matches = append(matches, Match{
+				NameHome:   names[home],
+				NameAway:   names[away],
+				HomeTeamID: home,
+				AwayTeamID: away,
+				Week:       round + 1,
+			})
+		}
+
+		// Rotate every slot except the first one clockwise
+		last := ids[n-1]
+		copy(ids[2:], ids[1:n-1])
+		ids[1] = last
+	}
+
+	if doubleRoundRobin {
+		// Mirroring week 1 straight after the last week would give teams a third home or away game in a row,
+		// so the second half plays the mirrored weeks 2..rounds first and week 1 last
+		firstHalf := matches
+		for week := 1; week <= rounds; week++ {
+			source := week%rounds + 1
+			for _, m := range firstHalf {
+				if m.Week != source {
+					continue
+				}
+				matches = append(matches, Match{
+					NameHome:   m.NameAway,
+					NameAway:   m.NameHome,
+					HomeTeamID: m.AwayTeamID,
+					AwayTeamID: m.HomeTeamID,
+					Week:       week + rounds,
+				})
+			}
+		}
+	}
+	return matches
+}
+
+// seasonLengthOf returns the last scheduled week in the given matches
+func seasonLengthOf(matches []Match) int {
+	length := 0
+	for _, m := range matches {
+		if m.Week > length {
+			length = m.Week
+		}
+	}
+	return length
+}
+
+// GenerateFixturesHandler handles the request to replace the schedule with a new round-robin
+func GenerateFixturesHandler(matchService MatchService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		type GenerateFixturesRequest struct {
+			DoubleRoundRobin *bool `json:"double_round_robin"`
+		}
+		var req GenerateFixturesRequest
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+		}
+
+		// Default to the home-and-away format the league has always used
+		doubleRoundRobin := true
+		if req.DoubleRoundRobin != nil {
+			doubleRoundRobin = *req.DoubleRoundRobin
+		}
+
+		matches, err := matchService.GenerateFixtures(doubleRoundRobin)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Fixtures generated successfully",
+			"weeks":   seasonLengthOf(matches),
+			"matches": matches,
+		})
+	}
+}
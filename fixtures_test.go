@@ -0,0 +1,99 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// longestRun returns the most home or away games in a row of any team, weeks without a game do not break a run
+func longestRun(teams []Team, matches []Match) int {
+	matches = slices.Clone(matches)
+	slices.SortStableFunc(matches, func(a, b Match) int { return a.Week - b.Week })
+	longest := 0
+	for _, t := range teams {
+		run, lastHome := 0, false
+		for _, m := range matches {
+			if m.HomeTeamID != t.ID && m.AwayTeamID != t.ID {
+				continue
+			}
+			home := m.HomeTeamID == t.ID
+			if run > 0 && home == lastHome {
+				run++
+			} else {
+				run, lastHome = 1, home
+			}
+			longest = max(longest, run)
+		}
+	}
+	return longest
+}
+
+func TestGenerateRoundRobin(t *testing.T) {
+	for n := 2; n <= 20; n++ {
+		teams := groupTeams(n)
+		for _, double := range []bool{false, true} {
+			matches := generateRoundRobin(teams, double)
+			legs := 1
+			if double {
+				legs = 2
+			}
+
+			// Every pair meets once per leg, and in a double round-robin once at each ground
+			meetings := make(map[[2]int]int)
+			for _, m := range matches {
+				if m.HomeTeamID == m.AwayTeamID {
+					t.Fatalf("n=%d double=%v: team %d plays itself", n, double, m.HomeTeamID)
+				}
+				meetings[[2]int{m.HomeTeamID, m.AwayTeamID}]++
+			}
+			if want := n * (n - 1) / 2 * legs; len(matches) != want {
+				t.Errorf("n=%d double=%v: %d matches, want %d", n, double, len(matches), want)
+			}
+			for i := 1; i <= n; i++ {
+				for j := i + 1; j <= n; j++ {
+					there, back := meetings[[2]int{i, j}], meetings[[2]int{j, i}]
+					if there+back != legs || double && there != 1 {
+						t.Errorf("n=%d double=%v: teams %d and %d meet %d times at %d and %d times at %d", n, double, i, j, there, i, back, j)
+					}
+				}
+			}
+
+			// Nobody plays twice in a week, and every week but the byes is full
+			weeks := (n + n%2 - 1) * legs
+			if got := seasonLengthOf(matches); got != weeks {
+				t.Errorf("n=%d double=%v: %d weeks, want %d", n, double, got, weeks)
+			}
+			playing := make(map[[2]int]bool)
+			for _, m := range matches {
+				for _, id := range []int{m.HomeTeamID, m.AwayTeamID} {
+					if playing[[2]int{m.Week, id}] {
+						t.Fatalf("n=%d double=%v: team %d plays twice in week %d", n, double, id, m.Week)
+					}
+					playing[[2]int{m.Week, id}] = true
+				}
+			}
+
+			// Home games are spread evenly, a single round-robin gives every team half its games at home give or take one
+			home := make(map[int]int)
+			for _, m := range matches {
+				home[m.HomeTeamID]++
+			}
+			for _, team := range teams {
+				games := (n - 1) * legs
+				if double && home[team.ID] != n-1 || 2*home[team.ID] < games-1 || 2*home[team.ID] > games+1 {
+					t.Errorf("n=%d double=%v: team %d plays %d of %d games at home", n, double, team.ID, home[team.ID], games)
+				}
+			}
+
+			if got := longestRun(teams, matches); got > 2 {
+				t.Errorf("n=%d double=%v: a team plays %d home or away games in a row", n, double, got)
+			}
+		}
+	}
+}
+
+func TestGenerateRoundRobinTooFewTeams(t *testing.T) {
+	if matches := generateRoundRobin(groupTeams(1), true); matches != nil {
+		t.Errorf("one team got %d matches", len(matches))
+	}
+}
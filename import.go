@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportSize limits the size of an uploaded results file
+const maxImportSize = 10 << 20
+
+// importWeekDays is the longest span of days one imported week covers
+const importWeekDays = 7
+
+// importColumns are the accepted headers of every column read, compared ignoring case.
+// The first names are those of the football-data.co.uk main leagues, the second those of its extra leagues.
+var importColumns = []struct {
+	field string
+	names []string
+}{
+	{"date", []string{"date"}},
+	{"home_team", []string{"hometeam", "home", "home_team"}},
+	{"away_team", []string{"awayteam", "away", "away_team"}},
+	{"home_goals", []string{"fthg", "hg", "home_goals"}},
+	{"away_goals", []string{"ftag", "ag", "away_goals"}},
+}
+
+// importDateLayouts are the accepted date formats, football-data.co.uk uses both day first forms
+var importDateLayouts = []string{"02/01/2006", "02/01/06", "2006-01-02"}
+
+// ImportRowError is a row of the file that could not be imported, row 1 is the header
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// ImportResult describes the season created from an imported file
+type ImportResult struct {
+	Season       Season           `json:"season"`
+	Matches      int              `json:"matches"`
+	Weeks        int              `json:"weeks"`
+	CreatedTeams []Team           `json:"created_teams"`
+	Errors       []ImportRowError `json:"errors,omitempty"`
+}
+
+// importedRow is a valid row of the file
+type importedRow struct {
+	date time.Time
+	ImportedResult
+}
+
+// ImportService interface for loading historical results
+type ImportService interface {
+	Import(leagueID int, name string, data io.Reader) (ImportResult, error)
+}
+
+// MyImportService implements ImportService interface
+type MyImportService struct {
+	store    LeagueStore
+	settings LeagueSettings
+}
+
+// Import reads played matches from a CSV file into a new archived season of a league.
+// Teams are matched to the stored ones by name ignoring case, missing teams are created with the base strength.
+// Nothing is stored when a row is invalid, the result then lists the error of every such row.
+func (s *MyImportService) Import(leagueID int, name string, data io.Reader) (ImportResult, error) {
+	rows, rowErrors, err := parseResults(data)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	if len(rowErrors) > 0 {
+		return ImportResult{Errors: rowErrors}, fmt.Errorf("%w: %d rows are invalid", errInvalidInput, len(rowErrors))
+	}
+	if len(rows) == 0 {
+		return ImportResult{}, fmt.Errorf("%w: the file has no results", errInvalidInput)
+	}
+	weeks := importWeeks(rows)
+
+	var result ImportResult
+	err = s.store.Atomic(func(store LeagueStore) error {
+		league, err := store.League(leagueID)
+		if err != nil {
+			return err
+		}
+		if league.Format != formatLeague {
+			return fmt.Errorf("%w: results can only be imported into a league, not a %s", errInvalidInput, league.Format)
+		}
+		if name == "" {
+			name = "Season " + rows[0].date.Format("2006")
+			if last := rows[len(rows)-1].date.Format("2006"); last != rows[0].date.Format("2006") {
+				name += "-" + last
+			}
+		}
+
+		allTeams, err := store.AllTeams()
+		if err != nil {
+			return err
+		}
+		byName := make(map[string]Team, len(allTeams))
+		for _, t := range allTeams {
+			byName[strings.ToLower(t.Name)] = t
+		}
+		var seasonTeams []Team
+		team := func(name string) (Team, error) {
+			key := strings.ToLower(name)
+			t, ok := byName[key]
+			if !ok {
+				created, err := store.CreateTeam(name, int(baseStrength))
+				if err != nil {
+					return Team{}, err
+				}
+				result.CreatedTeams = append(result.CreatedTeams, created)
+				t = created
+			}
+			if !slices.ContainsFunc(seasonTeams, func(s Team) bool { return s.ID == t.ID }) {
+				seasonTeams = append(seasonTeams, t)
+			}
+			byName[key] = t
+			return t, nil
+		}
+
+		var matches []Match
+		for i, r := range rows {
+			home, err := team(r.HomeTeam)
+			if err != nil {
+				return err
+			}
+			away, err := team(r.AwayTeam)
+			if err != nil {
+				return err
+			}
+			homeGoals, awayGoals := r.HomeGoals, r.AwayGoals
+			matches = append(matches, Match{
+				NameHome:   home.Name,
+				NameAway:   away.Name,
+				HomeTeamID: home.ID,
+				AwayTeamID: away.ID,
+				HomeGoals:  &homeGoals,
+				AwayGoals:  &awayGoals,
+				Week:       weeks[i],
+				Played:     true,
+			})
+		}
+
+		result.Season, err = store.CreateSeason(leagueID, name)
+		if err != nil {
+			return err
+		}
+		if err := store.AddSeasonTeams(result.Season.ID, seasonTeams); err != nil {
+			return err
+		}
+		repo := store.ForSeason(result.Season.ID)
+		if _, err := repo.AddMatches(matches); err != nil {
+			return err
+		}
+		if _, err := syncStandings(repo, s.settings); err != nil {
+			return err
+		}
+		if err := store.ArchiveSeason(result.Season.ID); err != nil {
+			return err
+		}
+		result.Season.Status = seasonArchived
+		result.Matches = len(matches)
+		result.Weeks = weeks[len(weeks)-1]
+		return nil
+	})
+	if err != nil {
+		return ImportResult{}, err
+	}
+	if result.CreatedTeams == nil {
+		result.CreatedTeams = []Team{}
+	}
+	return result, nil
+}
+
+// parseResults reads the rows of a results file in date order, rows of the same date keep the order of the file.
+// Blank rows are skipped and every invalid row is reported, an error is only returned when the file cannot be read at all.
+func parseResults(data io.Reader) ([]importedRow, []ImportRowError, error) {
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(content, []byte("\ufeff"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, fmt.Errorf("%w: the file is empty", errInvalidInput)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errInvalidInput, err)
+	}
+	columns := make(map[string]int, len(importColumns))
+	for _, column := range importColumns {
+		for i, h := range header {
+			if slices.Contains(column.names, strings.ToLower(strings.TrimSpace(h))) {
+				columns[column.field] = i
+				break
+			}
+		}
+		if _, ok := columns[column.field]; !ok {
+			return nil, nil, fmt.Errorf("%w: no %s column, expected one of %s", errInvalidInput, column.field, strings.Join(column.names, ", "))
+		}
+	}
+
+	var rows []importedRow
+	var rowErrors []ImportRowError
+	seen := make(map[[2]string]int) // first row of every fixture
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, ImportRowError{Row: row, Error: err.Error()})
+			continue
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		field := func(name string) string {
+			if i := columns[name]; i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		r, err := parseResultRow(field)
+		if err != nil {
+			rowErrors = append(rowErrors, ImportRowError{Row: row, Error: err.Error()})
+			continue
+		}
+		fixture := [2]string{strings.ToLower(r.HomeTeam), strings.ToLower(r.AwayTeam)}
+		if first, ok := seen[fixture]; ok {
+			rowErrors = append(rowErrors, ImportRowError{Row: row, Error: fmt.Sprintf("%s against %s is already on row %d", r.HomeTeam, r.AwayTeam, first)})
+			continue
+		}
+		seen[fixture] = row
+		rows = append(rows, r)
+	}
+
+	slices.SortStableFunc(rows, func(a, b importedRow) int { return a.date.Compare(b.date) })
+	return rows, rowErrors, nil
+}
+
+// parseResultRow validates the columns of one row
+func parseResultRow(field func(name string) string) (importedRow, error) {
+	var r importedRow
+	date := field("date")
+	var err error
+	for _, layout := range importDateLayouts {
+		if r.date, err = time.Parse(layout, date); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return importedRow{}, fmt.Errorf("date %q is not in dd/mm/yyyy, dd/mm/yy or yyyy-mm-dd format", date)
+	}
+
+	r.HomeTeam, r.AwayTeam = field("home_team"), field("away_team")
+	if err := validateTeamName(r.HomeTeam); err != nil {
+		return importedRow{}, fmt.Errorf("home team %s", err)
+	}
+	if err := validateTeamName(r.AwayTeam); err != nil {
+		return importedRow{}, fmt.Errorf("away team %s", err)
+	}
+	if strings.EqualFold(r.HomeTeam, r.AwayTeam) {
+		return importedRow{}, fmt.Errorf("%s cannot play itself", r.HomeTeam)
+	}
+
+	goals := func(name string) (int, error) {
+		value := field(name)
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%s must be a non-negative number, got %q", name, value)
+		}
+		return n, nil
+	}
+	if r.HomeGoals, err = goals("home_goals"); err != nil {
+		return importedRow{}, err
+	}
+	if r.AwayGoals, err = goals("away_goals"); err != nil {
+		return importedRow{}, err
+	}
+	return r, nil
+}
+
+// importWeeks numbers the weeks of rows in date order: a new week starts when a team of the match has already played
+// in the current week, or when the match is importWeekDays or more after the first match of the week
+func importWeeks(rows []importedRow) []int {
+	weeks := make([]int, len(rows))
+	week := 0
+	var start time.Time
+	var playing map[string]bool
+	for i, r := range rows {
+		home, away := strings.ToLower(r.HomeTeam), strings.ToLower(r.AwayTeam)
+		if week == 0 || playing[home] || playing[away] || r.date.Sub(start) >= importWeekDays*24*time.Hour {
+			week++
+			start = r.date
+			playing = make(map[string]bool)
+		}
+		playing[home], playing[away] = true, true
+		weeks[i] = week
+	}
+	return weeks
+}
+
+// runImport imports a results file given by path into a league, the first league by default
+func runImport(importService ImportService, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: usage: import FILE [LEAGUE_ID] [SEASON_NAME]", errInvalidInput)
+	}
+	leagueID := 1
+	if len(args) > 1 {
+		var err error
+		if leagueID, err = strconv.Atoi(args[1]); err != nil {
+			return fmt.Errorf("%w: league must be a number, got %q", errInvalidInput, args[1])
+		}
+	}
+	name := ""
+	if len(args) > 2 {
+		name = strings.TrimSpace(args[2])
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	result, err := importService.Import(leagueID, name, file)
+	for _, e := range result.Errors {
+		fmt.Printf("row %d: %s\n", e.Row, e.Error)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("league=%d season=%d name=%q matches=%d weeks=%d created_teams=%d\n",
+		result.Season.LeagueID, result.Season.ID, result.Season.Name, result.Matches, result.Weeks, len(result.CreatedTeams))
+	return nil
+}
+
+// ImportHandler handles the request to import a CSV results file into a new season.
+// The file is the request body or the file field of a multipart form, ?league_id= defaults to 1 and ?name= names the season.
+func ImportHandler(importService ImportService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		leagueID := 1
+		if value := c.Query("league_id"); value != "" {
+			var err error
+			leagueID, err = strconv.Atoi(value)
+			if err != nil || leagueID < 1 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("league_id must be a positive number, got %q", value)})
+				return
+			}
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+		var data io.Reader = c.Request.Body
+		if strings.HasPrefix(c.ContentType(), "multipart/") {
+			header, err := c.FormFile("file")
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "the form needs a file field"})
+				return
+			}
+			file, err := header.Open()
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			defer file.Close()
+			data = file
+		}
+
+		result, err := importService.Import(leagueID, strings.TrimSpace(c.Query("name")), data)
+		if err != nil {
+			response := gin.H{"error": err.Error()}
+			if len(result.Errors) > 0 {
+				response["rows"] = result.Errors
+			}
+			var tooLarge *http.MaxBytesError
+			status := errorStatus(err)
+			if errors.As(err, &tooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			c.JSON(status, response)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{
+			"message": "Results imported successfully",
+			"import":  result,
+		})
+	}
+}
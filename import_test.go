@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// importRow is a valid row played on a dd/mm/yyyy date
+func importRow(date, home, away string) importedRow {
+	day, _ := time.Parse("02/01/2006", date)
+	return importedRow{date: day, ImportedResult: ImportedResult{HomeTeam: home, AwayTeam: away}}
+}
+
+func TestParseResultsReadsEveryDateFormat(t *testing.T) {
+	file := "\ufeffDate,HomeTeam,AwayTeam,FTHG,FTAG\n" +
+		"17/08/2024,Arsenal,Wolves,2,0\n" +
+		"2024-08-16,Man United,Fulham,1,0\n" +
+		"18/08/24,Chelsea,Man City,0,2\n"
+	rows, rowErrors, err := parseResults(strings.NewReader(file))
+	if err != nil || len(rowErrors) > 0 {
+		t.Fatalf("parse: %v %v", err, rowErrors)
+	}
+
+	// Rows come back in date order
+	want := []importedRow{
+		{time.Date(2024, 8, 16, 0, 0, 0, 0, time.UTC), ImportedResult{"Man United", "Fulham", 1, 0}},
+		{time.Date(2024, 8, 17, 0, 0, 0, 0, time.UTC), ImportedResult{"Arsenal", "Wolves", 2, 0}},
+		{time.Date(2024, 8, 18, 0, 0, 0, 0, time.UTC), ImportedResult{"Chelsea", "Man City", 0, 2}},
+	}
+	if !slices.Equal(rows, want) {
+		t.Errorf("rows %v, want %v", rows, want)
+	}
+}
+
+func TestParseResultsAcceptsTheExtraLeagueHeaders(t *testing.T) {
+	rows, rowErrors, err := parseResults(strings.NewReader("Country,Date,Home,Away,HG,AG\nBrazil,13/04/2024,Palmeiras,Vitoria,2,1\n"))
+	if err != nil || len(rowErrors) > 0 || len(rows) != 1 || rows[0].ImportedResult != (ImportedResult{"Palmeiras", "Vitoria", 2, 1}) {
+		t.Errorf("parsed %v %v %v", rows, rowErrors, err)
+	}
+	if _, _, err := parseResults(strings.NewReader("Date,HomeTeam,AwayTeam,FTHG\n")); err == nil {
+		t.Error("a file without an away goals column was accepted")
+	}
+}
+
+func TestImportReportsEveryInvalidRow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	leagueService := &MyLeagueService{store: newMemoryStore(sampleTeams()), settings: benchmarkSettings()}
+	router := gin.New()
+	router.POST("/import", ImportHandler(&MyImportService{store: leagueService.store, settings: leagueService.settings}))
+
+	file := "Date,HomeTeam,AwayTeam,FTHG,FTAG\n" +
+		"16/08/2024,Liverpool,Leicester City,1,0\n" +
+		"16 Aug 2024,Liverpool,Manchester City,1,0\n" +
+		"17/08/2024,Liverpool,liverpool,1,0\n" +
+		"17/08/2024,Manchester City,Leicester City,-1,0\n" +
+		"17/08/2024,Liverpool,Leicester City,2,2\n" +
+		",,,,\n" +
+		"18/08/2024,,Leicester City,1,0\n"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/import", strings.NewReader(file)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body.String())
+	}
+
+	// The blank row is skipped, every other invalid row is reported with its line in the file
+	var response struct {
+		Rows []ImportRowError `json:"rows"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	var rows []int
+	for _, e := range response.Rows {
+		rows = append(rows, e.Row)
+	}
+	if !slices.Equal(rows, []int{3, 4, 5, 6, 8}) {
+		t.Errorf("invalid rows %v, want [3 4 5 6 8]: %+v", rows, response.Rows)
+	}
+	if seasons, _ := leagueService.Seasons(1); len(seasons) != 1 {
+		t.Errorf("a rejected file created a season: %+v", seasons)
+	}
+}
+
+func TestImportWeeksSplitsOnRepeatedTeamsAndSevenDays(t *testing.T) {
+	rows := []importedRow{
+		importRow("16/08/2024", "A", "B"),
+		importRow("17/08/2024", "C", "D"),
+		importRow("18/08/2024", "E", "F"),
+		// Eight days after the first match of week 1
+		importRow("24/08/2024", "A", "C"),
+		importRow("25/08/2024", "B", "D"),
+		// A has already played in week 2
+		importRow("26/08/2024", "A", "E"),
+		// Six days and then seven days after the first match of week 3, with teams that have not played in it
+		importRow("01/09/2024", "C", "D"),
+		importRow("02/09/2024", "B", "F"),
+	}
+	if weeks := importWeeks(rows); !slices.Equal(weeks, []int{1, 1, 1, 2, 2, 3, 3, 4}) {
+		t.Errorf("weeks %v, want [1 1 1 2 2 3 3 4]", weeks)
+	}
+}
+
+func TestImportCreatesAnArchivedSeason(t *testing.T) {
+	store := newMemoryStore(sampleTeams())
+	importService := &MyImportService{store: store, settings: benchmarkSettings()}
+	file := "Date,HomeTeam,AwayTeam,FTHG,FTAG\n" +
+		"17/08/2024,LIVERPOOL,Brentford,2,0\n" +
+		"24/08/2024,Brentford,liverpool,1,1\n"
+
+	result, err := importService.Import(1, "", strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Season.Name != "Season 2024" || result.Season.Status != seasonArchived || result.Matches != 2 || result.Weeks != 2 {
+		t.Errorf("imported %+v", result)
+	}
+	// Liverpool is matched ignoring case, Brentford is new
+	if len(result.CreatedTeams) != 1 || result.CreatedTeams[0].Name != "Brentford" || result.CreatedTeams[0].Strength != int(baseStrength) {
+		t.Errorf("created teams %+v", result.CreatedTeams)
+	}
+	table, err := store.ForSeason(result.Season.ID).Teams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := slices.IndexFunc(table, func(team Team) bool { return team.ID == 2 })
+	if i < 0 || table[i].Points != 4 || len(table) != 2 {
+		t.Errorf("imported table %+v, want Liverpool (team 2) on 4 points and Brentford", table)
+	}
+}
@@ -0,0 +1,628 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Competition formats, a league season is a round-robin table, a cup season a knockout bracket
+// and a tournament season a group stage followed by a knockout
+const (
+	formatLeague     = "league"
+	formatCup        = "cup"
+	formatTournament = "tournament"
+)
+
+// Season statuses, archived seasons keep their final table and results but can no longer be changed
+const (
+	seasonActive   = "active"
+	seasonArchived = "archived"
+)
+
+// League is a competition that is played over one or more seasons.
+// It can sit above another division, swapping teams with it when the seasons roll over.
+type League struct {
+	ID              int    `json:"id"`
+	Name            string `json:"name"`
+	DivisionBelowID *int   `json:"division_below_id"`
+	PromotionPlaces int    `json:"promotion_places"` // teams automatically swapped with the division below
+	PlayoffPlaces   int    `json:"playoff_places"`   // teams of the division below playing off for one more promotion, 0 for none
+	Format          string `json:"format"`           // league, cup or tournament, fixed at creation
+	Legs            int    `json:"legs"`             // legs of every knockout tie before the final
+	Groups          int    `json:"groups"`           // groups of a tournament
+	GroupQualifiers int    `json:"group_qualifiers"` // teams of every group going through to the knockout
+	BestPlaced      int    `json:"best_placed"`      // best teams placed just below the qualifiers that also go through
+	Engine          string `json:"engine"`           // match engine of every season, empty for the configured one
+}
+
+// Season is one edition of a league with its own teams, fixtures and deductions
+type Season struct {
+	ID       int    `json:"id"`
+	LeagueID int    `json:"league_id"`
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+}
+
+// LeagueOverview is a league together with all of its seasons
+type LeagueOverview struct {
+	League
+	Seasons []Season `json:"seasons"`
+}
+
+// NewTeam describes a team to create while setting up a league
+type NewTeam struct {
+	Name     string `json:"name"`
+	Strength int    `json:"strength"`
+}
+
+// LeagueService interface defines methods for managing leagues and their seasons
+type LeagueService interface {
+	Leagues() ([]LeagueOverview, error)
+	CreateLeague(l League, teamIDs []int, newTeams []NewTeam, doubleRoundRobin bool, rng *rand.Rand) (League, Season, error)
+	UpdateLeague(l League) (League, error)
+	Seasons(leagueID int) ([]Season, error)
+	StartSeason(leagueID int, name string, teamIDs []int, doubleRoundRobin, force bool, rng *rand.Rand) (Season, error)
+	CurrentSeason(leagueID int) (Season, error)
+	SeasonServices(leagueID, seasonID int) (seasonServices, error)
+	Rollover(leagueID int, rng *rand.Rand) ([]DivisionRollover, error)
+}
+
+// MyLeagueService implements LeagueService interface
+type MyLeagueService struct {
+	store    LeagueStore
+	settings LeagueSettings
+}
+
+// Leagues returns every league with its seasons
+func (s *MyLeagueService) Leagues() ([]LeagueOverview, error) {
+	leagues, err := s.store.Leagues()
+	if err != nil {
+		return nil, err
+	}
+	overviews := make([]LeagueOverview, len(leagues))
+	for i, l := range leagues {
+		seasons, err := s.store.Seasons(l.ID)
+		if err != nil {
+			return nil, err
+		}
+		l.Engine = s.settings.forLeague(l).Engine.Name()
+		overviews[i] = LeagueOverview{League: l, Seasons: seasons}
+	}
+	return overviews, nil
+}
+
+// CreateLeague creates a league with its first season between existing and newly created teams,
+// and schedules its fixtures or draws its cup bracket or tournament groups
+func (s *MyLeagueService) CreateLeague(l League, teamIDs []int, newTeams []NewTeam, doubleRoundRobin bool, rng *rand.Rand) (League, Season, error) {
+	var league League
+	var season Season
+	l.Engine = cmp.Or(l.Engine, s.settings.Engine.Name())
+	err := s.store.Atomic(func(store LeagueStore) error {
+		var err error
+		league, err = store.CreateLeague(l)
+		if err != nil {
+			return err
+		}
+		ids := append([]int{}, teamIDs...)
+		for _, t := range newTeams {
+			team, err := createTeam(store, t.Name, t.Strength)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, team.ID)
+		}
+		season, err = startSeason(store, league, "Season 1", ids, doubleRoundRobin, rng)
+		return err
+	})
+	return league, season, err
+}
+
+// UpdateLeague renames a league and sets the division below it with the number of promotion and playoff places.
+// A division sits below at most one league and the divisions may not form a cycle.
+func (s *MyLeagueService) UpdateLeague(l League) (League, error) {
+	err := s.store.Atomic(func(store LeagueStore) error {
+		stored, err := store.League(l.ID)
+		if err != nil {
+			return err
+		}
+		l.Format, l.Legs = stored.Format, stored.Legs
+		l.Engine = cmp.Or(l.Engine, stored.Engine)
+		l.Groups, l.GroupQualifiers, l.BestPlaced = stored.Groups, stored.GroupQualifiers, stored.BestPlaced
+		if l.DivisionBelowID == nil {
+			l.PromotionPlaces, l.PlayoffPlaces = 0, 0
+			return store.SaveLeague(l)
+		}
+
+		leagues, err := store.Leagues()
+		if err != nil {
+			return err
+		}
+		below := -1
+		for i, other := range leagues {
+			if other.ID == *l.DivisionBelowID {
+				below = i
+			}
+			if other.ID != l.ID && other.DivisionBelowID != nil && *other.DivisionBelowID == *l.DivisionBelowID {
+				return fmt.Errorf("%w: league %d is already the division below %s", errInvalidInput, *l.DivisionBelowID, other.Name)
+			}
+		}
+		if below < 0 {
+			return fmt.Errorf("%w: %d", errLeagueNotFound, *l.DivisionBelowID)
+		}
+		if l.Format != formatLeague || leagues[below].Format != formatLeague {
+			return fmt.Errorf("%w: only leagues can be linked as divisions", errInvalidInput)
+		}
+		// Walk down from the new division below, reaching this league again would close a cycle
+		for next := l.DivisionBelowID; next != nil; {
+			if *next == l.ID {
+				return fmt.Errorf("%w: league %d would end up below itself", errInvalidInput, l.ID)
+			}
+			i := slices.IndexFunc(leagues, func(other League) bool { return other.ID == *next })
+			next = leagues[i].DivisionBelowID
+		}
+		return store.SaveLeague(l)
+	})
+	if err != nil {
+		return League{}, err
+	}
+	l.Engine = s.settings.forLeague(l).Engine.Name()
+	return l, nil
+}
+
+// Seasons returns every season of a league, oldest first
+func (s *MyLeagueService) Seasons(leagueID int) ([]Season, error) {
+	if _, err := s.store.League(leagueID); err != nil {
+		return nil, err
+	}
+	return s.store.Seasons(leagueID)
+}
+
+// StartSeason archives the running season of a league and starts a new one.
+// The name defaults to "Season N" and the teams default to those of the current season.
+// A running season with unplayed matches is only cut short with force.
+func (s *MyLeagueService) StartSeason(leagueID int, name string, teamIDs []int, doubleRoundRobin, force bool, rng *rand.Rand) (Season, error) {
+	var season Season
+	err := s.store.Atomic(func(store LeagueStore) error {
+		// Concurrent starts would both find the running season active and each start a new one
+		if err := store.LockLeagues(leagueID); err != nil {
+			return err
+		}
+		league, err := store.League(leagueID)
+		if err != nil {
+			return err
+		}
+		seasons, err := store.Seasons(leagueID)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			name = fmt.Sprintf("Season %d", len(seasons)+1)
+		}
+
+		if len(seasons) > 0 {
+			current := currentSeason(seasons)
+			repo := store.ForSeason(current.ID)
+			if current.Status == seasonActive {
+				if !force {
+					matches, err := repo.Matches()
+					if err != nil {
+						return err
+					}
+					if slices.ContainsFunc(matches, func(m Match) bool { return !m.Played }) {
+						return fmt.Errorf("%w: %s, send force to archive it anyway", errSeasonNotFinished, current.Name)
+					}
+				}
+				// Freeze the final table of the season being archived
+				if _, err := syncStandings(repo, s.settings); err != nil {
+					return err
+				}
+			}
+			if len(teamIDs) == 0 {
+				teams, err := repo.Teams()
+				if err != nil {
+					return err
+				}
+				for _, t := range teams {
+					teamIDs = append(teamIDs, t.ID)
+				}
+			}
+		}
+		if err := store.ArchiveSeasons(leagueID); err != nil {
+			return err
+		}
+
+		season, err = startSeason(store, league, name, teamIDs, doubleRoundRobin, rng)
+		return err
+	})
+	return season, err
+}
+
+// CurrentSeason returns the active season of a league, or its latest one when every season is archived
+func (s *MyLeagueService) CurrentSeason(leagueID int) (Season, error) {
+	seasons, err := s.Seasons(leagueID)
+	if err != nil {
+		return Season{}, err
+	}
+	if len(seasons) == 0 {
+		return Season{}, errSeasonNotFound
+	}
+	return currentSeason(seasons), nil
+}
+
+// seasonServices are the services working on one season, with the format of its league
+type seasonServices struct {
+	format     string
+	teams      *MyTeamService
+	matches    *MyMatchService
+	cup        *MyCupService
+	tournament *MyTournamentService
+}
+
+// SeasonServices returns the services working on one season of a league
+func (s *MyLeagueService) SeasonServices(leagueID, seasonID int) (seasonServices, error) {
+	season, err := s.store.Season(seasonID)
+	if err != nil {
+		return seasonServices{}, err
+	}
+	if season.LeagueID != leagueID {
+		return seasonServices{}, errSeasonNotFound
+	}
+	league, err := s.store.League(leagueID)
+	if err != nil {
+		return seasonServices{}, err
+	}
+	// Every season is played with the match engine of its league
+	settings := s.settings.forLeague(league)
+	repo := s.store.ForSeason(season.ID)
+	teamService := &MyTeamService{repo: repo, settings: settings}
+	return seasonServices{
+		format:     league.Format,
+		teams:      teamService,
+		matches:    &MyMatchService{repo: repo, teamService: teamService, settings: settings},
+		cup:        &MyCupService{repo: repo, settings: settings, legs: league.Legs},
+		tournament: &MyTournamentService{repo: repo, settings: settings, league: league},
+	}, nil
+}
+
+// currentSeason picks the active season from a league's seasons, falling back to the latest one
+func currentSeason(seasons []Season) Season {
+	for _, season := range seasons {
+		if season.Status == seasonActive {
+			return season
+		}
+	}
+	return seasons[len(seasons)-1]
+}
+
+// startSeason creates an active season for the given teams and schedules a round-robin between them,
+// for a cup seeds the teams by strength and draws the first round of the bracket,
+// and for a tournament draws the groups and schedules a round-robin inside each of them
+func startSeason(store LeagueStore, league League, name string, teamIDs []int, doubleRoundRobin bool, rng *rand.Rand) (Season, error) {
+	all, err := store.AllTeams()
+	if err != nil {
+		return Season{}, err
+	}
+	byID := make(map[int]Team, len(all))
+	for _, t := range all {
+		byID[t.ID] = t
+	}
+
+	var teams []Team
+	seen := make(map[int]bool)
+	for _, id := range teamIDs {
+		t, ok := byID[id]
+		if !ok {
+			return Season{}, fmt.Errorf("%w: %d", errTeamNotFound, id)
+		}
+		if seen[id] {
+			return Season{}, fmt.Errorf("%w: team %d is listed twice", errInvalidInput, id)
+		}
+		seen[id] = true
+		teams = append(teams, t)
+	}
+	if len(teams) < 2 {
+		return Season{}, fmt.Errorf("%w: a season needs at least two teams", errInvalidInput)
+	}
+
+	var fixtures []Match
+	switch league.Format {
+	case formatCup:
+		// The strongest team is the first seed
+		slices.SortStableFunc(teams, func(a, b Team) int { return cmp.Compare(b.Strength, a.Strength) })
+		for i := range teams {
+			teams[i].Seed = i + 1
+		}
+		fixtures = pendingCupMatches(buildCupBracket(teams, nil, league.Legs), league.Legs, 1)
+	case formatTournament:
+		if teams, err = drawGroups(teams, league, rng); err != nil {
+			return Season{}, err
+		}
+		fixtures = groupStageFixtures(teams, doubleRoundRobin)
+	default:
+		for i := range teams {
+			teams[i].Seed = i + 1
+		}
+		fixtures = generateRoundRobin(teams, doubleRoundRobin)
+	}
+
+	season, err := store.CreateSeason(league.ID, name)
+	if err != nil {
+		return Season{}, err
+	}
+	if err := store.AddSeasonTeams(season.ID, teams); err != nil {
+		return Season{}, err
+	}
+	if _, err := store.ForSeason(season.ID).ReplaceMatches(fixtures); err != nil {
+		return Season{}, err
+	}
+	return season, nil
+}
+
+// pathID reads a positive integer path parameter
+func pathID(c *gin.Context, name string) (int, error) {
+	id, err := strconv.Atoi(c.Param(name))
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("%w: %s must be a positive number", errInvalidInput, name)
+	}
+	return id, nil
+}
+
+// ListLeaguesHandler handles the request for every league with its seasons
+func ListLeaguesHandler(leagueService LeagueService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		leagues, err := leagueService.Leagues()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"leagues": leagues})
+	}
+}
+
+// CreateLeagueHandler handles the request to create a league with its first season
+func CreateLeagueHandler(leagueService LeagueService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		type CreateLeagueRequest struct {
+			Name             string    `json:"name"`
+			Format           string    `json:"format"`
+			Legs             int       `json:"legs"`
+			Groups           int       `json:"groups"`
+			GroupQualifiers  int       `json:"group_qualifiers"`
+			BestPlaced       int       `json:"best_placed"`
+			Engine           string    `json:"engine"`
+			TeamIDs          []int     `json:"team_ids"`
+			Teams            []NewTeam `json:"teams"`
+			DoubleRoundRobin *bool     `json:"double_round_robin"`
+		}
+		var req CreateLeagueRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name must not be empty"})
+			return
+		}
+		for i, t := range req.Teams {
+			req.Teams[i].Name = strings.TrimSpace(t.Name)
+			err := validateTeamName(req.Teams[i].Name)
+			if err == nil {
+				err = validateStrength(t.Strength)
+			}
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("team %d: %s", i+1, err)})
+				return
+			}
+		}
+		// Leagues are the default format, knockout ties are single matches unless two legs are asked for
+		league := League{Name: req.Name, Format: cmp.Or(req.Format, formatLeague), Legs: cmp.Or(req.Legs, 1), Engine: req.Engine}
+		if req.Engine != "" {
+			if _, err := matchEngineByName(req.Engine); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if league.Format != formatLeague && league.Format != formatCup && league.Format != formatTournament {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format must be league, cup or tournament"})
+			return
+		}
+		if league.Legs != 1 && (league.Legs != 2 || league.Format == formatLeague) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "legs must be 1, or 2 for a cup or tournament"})
+			return
+		}
+		if league.Format == formatTournament {
+			// Two groups with the top two of each going through, like a classic eight team tournament
+			league.Groups, league.GroupQualifiers, league.BestPlaced = cmp.Or(req.Groups, 2), cmp.Or(req.GroupQualifiers, 2), req.BestPlaced
+			if league.Groups < 2 || league.Groups > 26 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "groups must be between 2 and 26"})
+				return
+			}
+			if league.GroupQualifiers < 1 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "group_qualifiers must be positive"})
+				return
+			}
+			if league.BestPlaced < 0 || league.BestPlaced >= league.Groups {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "best_placed must be between 0 and one less than the number of groups"})
+				return
+			}
+		}
+		doubleRoundRobin := true
+		if req.DoubleRoundRobin != nil {
+			doubleRoundRobin = *req.DoubleRoundRobin
+		}
+		seed, err := requestSeed(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		league, season, err := leagueService.CreateLeague(league, req.TeamIDs, req.Teams, doubleRoundRobin, newRNG(seed))
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{
+			"league": league,
+			"season": season,
+			"seed":   seed,
+		})
+	}
+}
+
+// UpdateLeagueHandler handles the request to rename a league and set the division below it
+func UpdateLeagueHandler(leagueService LeagueService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		leagueID, err := pathID(c, "league_id")
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		var req League
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+		req.ID = leagueID
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name must not be empty"})
+			return
+		}
+		if req.PromotionPlaces < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "promotion_places must not be negative"})
+			return
+		}
+		if req.Engine != "" {
+			if _, err := matchEngineByName(req.Engine); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		// Playoffs are a knockout, so the places must fill every round
+		if p := req.PlayoffPlaces; p != 0 && (p < 2 || p&(p-1) != 0) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "playoff_places must be 0 or a power of two such as 2, 4 or 8"})
+			return
+		}
+
+		league, err := leagueService.UpdateLeague(req)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"league": league})
+	}
+}
+
+// ListSeasonsHandler handles the request for the seasons of a league
+func ListSeasonsHandler(leagueService LeagueService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		leagueID, err := pathID(c, "league_id")
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		seasons, err := leagueService.Seasons(leagueID)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"seasons": seasons})
+	}
+}
+
+// StartSeasonHandler handles the request to archive the running season of a league and start the next one
+func StartSeasonHandler(leagueService LeagueService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		leagueID, err := pathID(c, "league_id")
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		type StartSeasonRequest struct {
+			Name             string `json:"name"`
+			TeamIDs          []int  `json:"team_ids"`
+			DoubleRoundRobin *bool  `json:"double_round_robin"`
+			Force            bool   `json:"force"` // archive a running season with unplayed matches
+		}
+		var req StartSeasonRequest
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+		}
+		doubleRoundRobin := true
+		if req.DoubleRoundRobin != nil {
+			doubleRoundRobin = *req.DoubleRoundRobin
+		}
+		seed, err := requestSeed(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		season, err := leagueService.StartSeason(leagueID, strings.TrimSpace(req.Name), req.TeamIDs, doubleRoundRobin, req.Force, newRNG(seed))
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{
+			"season": season,
+			"seed":   seed,
+		})
+	}
+}
+
+// seasonScope resolves the services a season-scoped request works on
+type seasonScope func(c *gin.Context) (seasonServices, error)
+
+// pathSeasonScope takes the league and season from the request path
+func pathSeasonScope(leagueService LeagueService) seasonScope {
+	return func(c *gin.Context) (seasonServices, error) {
+		leagueID, err := pathID(c, "league_id")
+		if err != nil {
+			return seasonServices{}, err
+		}
+		seasonID, err := pathID(c, "season_id")
+		if err != nil {
+			return seasonServices{}, err
+		}
+		return leagueService.SeasonServices(leagueID, seasonID)
+	}
+}
+
+// currentSeasonScope follows the current season of a league, so the unscoped endpoints keep working after a new season starts
+func currentSeasonScope(leagueService LeagueService, leagueID int) seasonScope {
+	return func(c *gin.Context) (seasonServices, error) {
+		season, err := leagueService.CurrentSeason(leagueID)
+		if err != nil {
+			return seasonServices{}, err
+		}
+		return leagueService.SeasonServices(leagueID, season.ID)
+	}
+}
+
+// inSeason builds the handler for the season a request is scoped to, refusing seasons of another format.
+// An empty format accepts every season.
+func inSeason(scope seasonScope, format string, handler func(services seasonServices) gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		services, err := scope(c)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		if format != "" && services.format != format {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s is not available in a %s season", c.FullPath(), services.format)})
+			return
+		}
+		handler(services)(c)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestStartSeasonRefusesUnplayedMatches(t *testing.T) {
+	leagueService := &MyLeagueService{store: newMemoryStore(sampleTeams()), settings: benchmarkSettings()}
+
+	if _, err := leagueService.StartSeason(1, "", nil, true, false, newRNG(1)); !errors.Is(err, errSeasonNotFinished) {
+		t.Fatalf("starting a season over unplayed matches gave %v, want %v", err, errSeasonNotFinished)
+	}
+	if seasons, _ := leagueService.Seasons(1); len(seasons) != 1 || seasons[0].Status != seasonActive {
+		t.Fatalf("a refused start changed the seasons: %+v", seasons)
+	}
+
+	season, err := leagueService.StartSeason(1, "", nil, true, true, newRNG(1))
+	if err != nil {
+		t.Fatalf("forced start: %v", err)
+	}
+	if season.Name != "Season 2" || season.Status != seasonActive {
+		t.Errorf("forced start created %+v", season)
+	}
+}
+
+func TestStartSeasonAfterEveryMatchIsPlayed(t *testing.T) {
+	leagueService := &MyLeagueService{store: newMemoryStore(sampleTeams()), settings: benchmarkSettings()}
+	services, err := leagueService.SeasonServices(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := newRNG(1)
+	for {
+		if _, _, err := services.matches.PlayWeek(rng); err != nil {
+			break
+		}
+	}
+
+	if _, err := leagueService.StartSeason(1, "", nil, true, false, newRNG(1)); err != nil {
+		t.Fatalf("starting a season after the last match: %v", err)
+	}
+	seasons, _ := leagueService.Seasons(1)
+	if len(seasons) != 2 || seasons[0].Status != seasonArchived || seasons[1].Status != seasonActive {
+		t.Errorf("seasons after the start: %+v", seasons)
+	}
+}
+
+func TestConcurrentStartsLeaveOneActiveSeason(t *testing.T) {
+	leagueService := &MyLeagueService{store: sqliteTestStore(t), settings: benchmarkSettings()}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = leagueService.StartSeason(1, "", nil, true, true, newRNG(int64(i)))
+		}()
+	}
+	wg.Wait()
+
+	started := 0
+	for _, err := range errs {
+		if err == nil {
+			started++
+		} else if !errors.Is(err, errConflict) {
+			t.Errorf("concurrent start: %v", err)
+		}
+	}
+	seasons, err := leagueService.Seasons(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	active := 0
+	for _, season := range seasons {
+		if season.Status == seasonActive {
+			active++
+		}
+	}
+	if active != 1 || len(seasons) != started+1 {
+		t.Errorf("%d starts left %d seasons with %d active: %+v", started, len(seasons), active, seasons)
+	}
+}
@@ -1,425 +1,733 @@
 package main
 
 import (
-    "database/sql"
-    "fmt"
-    "log"
-    "net/http"
-
-    "github.com/gin-gonic/gin"
-    _ "github.com/go-sql-driver/mysql"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/go-sql-driver/mysql"
 )
 
 // --- Structs for domain models ---
 
 // Team represents a football team with its attributes
 type Team struct {
-    ID           int    `json:"id"`
-    Name         string `json:"name"`
-    Strength     int    `json:"strength"`
-    Points       int    `json:"points"`
-    GoalsFor     int    `json:"goals_for"`
-    GoalsAgainst int    `json:"goals_against"`
-    GoalDiff     int    `json:"goal_diff"`
-    Wins         int    `json:"wins"`
-    Draws        int    `json:"draws"`
-    Losses       int    `json:"losses"`
+	ID             int     `json:"id"`
+	Name           string  `json:"name"`
+	Strength       int     `json:"strength"`
+	Points         int     `json:"points"`
+	GoalsFor       int     `json:"goals_for"`
+	GoalsAgainst   int     `json:"goals_against"`
+	GoalDiff       int     `json:"goal_diff"`
+	Wins           int     `json:"wins"`
+	Draws          int     `json:"draws"`
+	Losses         int     `json:"losses"`
+	FairPlayPoints int     `json:"fair_play_points"`        // disciplinary points, fewer ranks higher on the fair_play tiebreaker
+	PointsDeducted int     `json:"points_deducted"`         // total of the deductions ledger, already subtracted from Points
+	DecidedBy      string  `json:"decided_by,omitempty"`    // rule that separated the team from the next one in the table
+	GamesInHand    int     `json:"games_in_hand,omitempty"` // matches fewer played than the team with the most
+	Rating         float64 `json:"rating,omitempty"`        // Elo rating replayed from the played matches, 0 when ratings are disabled
+	Seed           int     `json:"-"`                       // position in the season's entry list, cups draw their bracket from it
+	Group          int     `json:"-"`                       // group of a tournament season from 1, 0 outside tournaments
 }
 
 // Match struct with its attributes
 type Match struct {
-    ID         int    `json:"id"`
-    NameHome   string `json:"name_home"`
-    NameAway   string `json:"name_away"`
-    HomeTeamID int    `json:"home_team_id"`
-    AwayTeamID int    `json:"away_team_id"`
-    HomeGoals  *int   `json:"home_goals"`
-    AwayGoals  *int   `json:"away_goals"`
-	Week 	   int    `json:"week"`
-    Played     bool   `json:"played"`
+	ID            int    `json:"id"`
+	NameHome      string `json:"name_home"`
+	NameAway      string `json:"name_away"`
+	HomeTeamID    int    `json:"home_team_id"`
+	AwayTeamID    int    `json:"away_team_id"`
+	HomeGoals     *int   `json:"home_goals"`
+	AwayGoals     *int   `json:"away_goals"`
+	Week          int    `json:"week"`
+	Played        bool   `json:"played"`
+	SeasonID      int    `json:"season_id"`
+	Round         int    `json:"round,omitempty"` // cup round, 0 for league matches
+	Leg           int    `json:"leg,omitempty"`
+	Tie           int    `json:"tie,omitempty"`        // position of the tie within its cup round
+	ExtraTime     bool   `json:"extra_time,omitempty"` // goals include extra time
+	HomePenalties *int   `json:"home_penalties,omitempty"`
+	AwayPenalties *int   `json:"away_penalties,omitempty"`
+	Postponed     bool   `json:"postponed,omitempty"` // waiting for a new week, PlayWeek skips it until it is rescheduled
 }
 
 // WeeklyResult struct used to return weekly results in the /play-all endpoint
 type WeeklyResult struct {
-    Week      int    `json:"week"`
-    Standings []Team `json:"standings"`
+	Week      int    `json:"week"`
+	Standings []Team `json:"standings"`
 }
 
 // --- Interfaces ---
 
 // TeamService interface defines methods for managing teams
 type TeamService interface {
-    GetTeams() ([]Team, error)
-    ResetTeams() error
+	GetTeams() ([]Team, error)
+	RebuildStandings() ([]Team, error)
+	CheckStandings() ([]StandingsDrift, error)
+	Deductions() ([]Deduction, error)
+	DeductPoints(d Deduction) (Deduction, []Team, error)
+	RemoveDeduction(id int) ([]Team, error)
+	SetFairPlayPoints(teamID, points int) ([]Team, error)
+	Ratings() ([]TeamRatings, error)
 }
 
 // MatchService interface defines methods for managing matches
 type MatchService interface {
-    GetMatches() ([]Match, error)
-    PlayWeek() (int, []Team, error)
-    ResetMatches() error
-	probabilities_Message(teamService *MyTeamService, matchService *MyMatchService, week int) (interface{}, error)
+	GetMatches() ([]Match, error)
+	PlayWeek(rng *rand.Rand) (int, []Team, error)
+	ChangeMatchResult(matchID, homeGoals, awayGoals int) (int, []Team, error)
+	SeasonLength() (int, error)
+	GenerateFixtures(doubleRoundRobin bool) ([]Match, error)
+	AddFixture(homeTeamID, awayTeamID, week int) (Match, error)
+	RescheduleMatch(id, week int) (Match, error)
+	PostponeMatch(id int) (Match, error)
+	CancelMatch(id int) error
+	Prediction(id int) (MatchPrediction, error)
+	Predictions(week int) (int, []MatchPrediction, error)
+	Backtest() (BacktestReport, error)
+	Settings() LeagueSettings
+	probabilities_Message(teamService TeamService, week int, rng *rand.Rand, simulations int) (interface{}, error)
 }
 
 // --- Structs implementing interfaces ---
 
 // myTeamService implements TeamService interface
 type MyTeamService struct {
-    db *sql.DB
+	repo     LeagueRepository
+	settings LeagueSettings
 }
 
 // myMatchService implements MatchService interface
 type MyMatchService struct {
-    db          *sql.DB
-    teamService TeamService
+	repo        LeagueRepository
+	teamService TeamService
+	settings    LeagueSettings
 }
 
 // --- TeamService methods ---
 
-// GetTeams retrieves all teams from the database
+// GetTeams retrieves all teams with their standings computed from the played matches
 func (s *MyTeamService) GetTeams() ([]Team, error) {
-    rows, err := s.db.Query(`SELECT id, name, strength, points, goals_for, goals_against, goal_diff, wins, draws, losses 
-						    FROM teams`)
-    if err != nil {
-        return nil, err
-    }
-    defer rows.Close()
-
-    var teams []Team
-    for rows.Next() {
-        var t Team
-        if err := rows.Scan(&t.ID, &t.Name, &t.Strength, &t.Points, &t.GoalsFor, &t.GoalsAgainst, &t.GoalDiff, &t.Wins, &t.Draws, &t.Losses); err != nil {
-            return nil, err
-        }
-        teams = append(teams, t)
-    }
-    return teams, nil
+	teams, err := s.repo.Teams()
+	if err != nil {
+		return nil, err
+	}
+	matches, err := s.repo.Matches()
+	if err != nil {
+		return nil, err
+	}
+	return computeStandings(teams, matches, s.settings), nil
+}
+
+// RebuildStandings overwrites the stored counters with the table computed from the played matches
+func (s *MyTeamService) RebuildStandings() ([]Team, error) {
+	var teams []Team
+	err := s.repo.Atomic(func(repo LeagueRepository) error {
+		var err error
+		teams, err = syncStandings(repo, s.settings)
+		return err
+	})
+	return teams, err
+}
+
+// CheckStandings reports every stored counter that differs from the table computed from the played matches
+func (s *MyTeamService) CheckStandings() ([]StandingsDrift, error) {
+	stored, err := s.repo.Teams()
+	if err != nil {
+		return nil, err
+	}
+	matches, err := s.repo.Matches()
+	if err != nil {
+		return nil, err
+	}
+	return compareStandings(stored, computeStandings(stored, matches, s.settings)), nil
+}
+
+// Deductions returns the points deductions ledger
+func (s *MyTeamService) Deductions() ([]Deduction, error) {
+	return s.repo.Deductions()
+}
+
+// DeductPoints records a points deduction for a team and returns it with the updated standings
+func (s *MyTeamService) DeductPoints(d Deduction) (Deduction, []Team, error) {
+	var teams []Team
+	err := s.repo.Atomic(func(repo LeagueRepository) error {
+		stored, err := repo.Teams()
+		if err != nil {
+			return err
+		}
+		if !slices.ContainsFunc(stored, func(t Team) bool { return t.ID == d.TeamID }) {
+			return fmt.Errorf("%w: %d", errTeamNotFound, d.TeamID)
+		}
+
+		d, err = repo.AddDeduction(d)
+		if err != nil {
+			return err
+		}
+		teams, err = syncStandings(repo, s.settings)
+		return err
+	})
+	if err != nil {
+		return Deduction{}, nil, err
+	}
+	return d, teams, nil
 }
 
-// ResetTeams resets all teams to their initial state
-func (s *MyTeamService) ResetTeams() error {
-    _, err := s.db.Exec(`UPDATE teams 
-						SET points = 0,
-							goals_for = 0,
-							goals_against = 0,
-							goal_diff = 0,
-							wins = 0,
-							draws = 0,
-							losses = 0
-    `)
-    return err
+// RemoveDeduction deletes a points deduction from the ledger and returns the updated standings
+func (s *MyTeamService) RemoveDeduction(id int) ([]Team, error) {
+	var teams []Team
+	err := s.repo.Atomic(func(repo LeagueRepository) error {
+		if err := repo.DeleteDeduction(id); err != nil {
+			return err
+		}
+		var err error
+		teams, err = syncStandings(repo, s.settings)
+		return err
+	})
+	return teams, err
 }
 
+// SetFairPlayPoints sets the disciplinary points of a team for the fair_play tiebreaker and returns the updated standings
+func (s *MyTeamService) SetFairPlayPoints(teamID, points int) ([]Team, error) {
+	var teams []Team
+	err := s.repo.Atomic(func(repo LeagueRepository) error {
+		stored, err := repo.Teams()
+		if err != nil {
+			return err
+		}
+		i := slices.IndexFunc(stored, func(t Team) bool { return t.ID == teamID })
+		if i < 0 {
+			return fmt.Errorf("%w: %d", errTeamNotFound, teamID)
+		}
+		stored[i].FairPlayPoints = points
+		if err := repo.SaveStandings(stored); err != nil {
+			return err
+		}
+		teams, err = syncStandings(repo, s.settings)
+		return err
+	})
+	return teams, err
+}
 
 // --- MatchService methods ---
 
-// GetMatches retrieves all matches from the database
+// GetMatches retrieves all matches ordered by week
 func (s *MyMatchService) GetMatches() ([]Match, error) {
-    rows, err := s.db.Query(`SELECT id, name_home, name_away, home_team_id, away_team_id, home_goals, away_goals, week, played 
-							FROM matches`)
-    if err != nil {
-        return nil, err
-    }
-    defer rows.Close()
-
-    var matches []Match 
-    for rows.Next() {
-        var m Match
-        var homeGoals, awayGoals sql.NullInt64
-        if err := rows.Scan(&m.ID, &m.NameHome, &m.NameAway, &m.HomeTeamID, &m.AwayTeamID, &homeGoals, &awayGoals, &m.Week, &m.Played); err != nil {
-            return nil, err
-        }
-        if homeGoals.Valid {
-            val := int(homeGoals.Int64)
-            m.HomeGoals = &val
-        }
-        if awayGoals.Valid {
-            val := int(awayGoals.Int64)
-            m.AwayGoals = &val
-        }
-        matches = append(matches, m)
-    }
-    return matches, nil
+	return s.repo.Matches()
 }
 
-// This function simulates a week of matches, updates the scores, and returns the standings
-func (s *MyMatchService) PlayWeek() (int, []Team, error) {
-    var lastPlayedWeek sql.NullInt64
-    err := s.db.QueryRow("SELECT MAX(week) FROM matches WHERE played = true").Scan(&lastPlayedWeek)
-    if err != nil {
-        return 0, nil, err
-    }
-
-	// Determine the ne,xt week to play
-    nextWeek := 1
-    if lastPlayedWeek.Valid {
-        if lastPlayedWeek.Int64 < 6 {
-            nextWeek = int(lastPlayedWeek.Int64) + 1
-        } else {
-            return 0, nil, fmt.Errorf("Season has ended")
-        }
-    }
-
-
-    rows, err := s.db.Query("SELECT id, home_team_id, away_team_id FROM matches WHERE week = ? AND played = false", nextWeek)
-    if err != nil {
-        return 0, nil, err
-    }
-    defer rows.Close()
-
-	// Fr each match simulate the result and update the database
-    for rows.Next() {
-        var id, homeID, awayID int
-        if err := rows.Scan(&id, &homeID, &awayID); err != nil {
-            return 0, nil, err
-        }
-        var homeStrength, awayStrength int
-        err := s.db.QueryRow("SELECT strength FROM teams WHERE id = ?", homeID).Scan(&homeStrength)
-        if err != nil {
-            return 0, nil, err
-        }
-        err = s.db.QueryRow("SELECT strength FROM teams WHERE id = ?", awayID).Scan(&awayStrength)
-        if err != nil {
-            return 0, nil, err
-        }
-
-		// Simulate the match result
-        home_goals, away_goals := simulateMatch(homeStrength, awayStrength)
-
-        // Update the match result in the database
-        _, err = s.db.Exec("UPDATE matches SET home_goals = ?, away_goals = ?, played = true WHERE id = ?", home_goals, away_goals, id)
-        if err != nil {
-            return 0, nil, err
-        }
-        _, err = s.db.Exec("UPDATE teams SET goals_for = goals_for + ?, goals_against = goals_against + ?, goal_diff = goal_diff + ? WHERE id = ?", home_goals, away_goals, home_goals-away_goals, homeID)
-        if err != nil {
-            return 0, nil, err
-        }
-        _, err = s.db.Exec("UPDATE teams SET goals_for = goals_for + ?, goals_against = goals_against + ?, goal_diff = goal_diff + ? WHERE id = ?", away_goals, home_goals, away_goals-home_goals, awayID)
-        if err != nil {
-            return 0, nil, err
-        }
-
-		// UPDATE the teams' points and stats based on the match result
-
-		// case1: Home team wins
-        if home_goals > away_goals { 
-            _, err = s.db.Exec("UPDATE teams SET points = points + 3, wins = wins + 1 WHERE id = ?", homeID)
-            if err != nil {
-                return 0, nil, err
-            }
-            _, err = s.db.Exec("UPDATE teams SET losses = losses + 1 WHERE id = ?", awayID)
-            if err != nil {
-                return 0, nil, err
-            }
-        }
-		// case2: Away team wins
-        if home_goals < away_goals { 
-            _, err = s.db.Exec("UPDATE teams SET points = points + 3, wins = wins + 1 WHERE id = ?", awayID)
-            if err != nil {
-                return 0, nil, err
-            }
-            _, err = s.db.Exec("UPDATE teams SET losses = losses + 1 WHERE id = ?", homeID)
-            if err != nil {
-                return 0, nil, err
-            }
-        }
-		// case3: Draw, both teams get 1 point
-        if home_goals == away_goals { 
-            _, err = s.db.Exec("UPDATE teams SET points = points + 1, draws = draws + 1 WHERE id = ?", awayID)
-            if err != nil {
-                return 0, nil, err
-            }
-            _, err = s.db.Exec("UPDATE teams SET points = points + 1, draws = draws + 1 WHERE id = ?", homeID)
-            if err != nil {
-                return 0, nil, err
-            }
-        }
-    }
-
-    // Get updated standings
-    rows, err = s.db.Query(`SELECT id, name, strength, points, goals_for, goals_against, goal_diff, wins, draws, losses 
-						   FROM teams
-						   ORDER BY points DESC, goal_diff DESC, goals_for DESC`)
-    if err != nil {
-        return 0, nil, err
-    }
-    defer rows.Close()
-
-	// Collect the standings after the week has been played
-    var teams []Team
-    for rows.Next() {
-        var t Team
-        if err := rows.Scan(&t.ID, &t.Name, &t.Strength, &t.Points, &t.GoalsFor, &t.GoalsAgainst, &t.GoalDiff, &t.Wins, &t.Draws, &t.Losses); err != nil {
-            return 0, nil, err
-        }
-        teams = append(teams, t)
-    }
+// This function simulates a week of matches, updates the scores, and returns the standings.
+// Everything runs in one atomic block holding the season lock, so concurrent calls play consecutive weeks.
+func (s *MyMatchService) PlayWeek(rng *rand.Rand) (int, []Team, error) {
+	var nextWeek int
+	var teams []Team
+	err := s.repo.Atomic(func(repo LeagueRepository) error {
+		matches, err := repo.Matches()
+		if err != nil {
+			return err
+		}
+		stored, err := repo.Teams()
+		if err != nil {
+			return err
+		}
+
+		seasonLength := s.settings.seasonLength(matches)
+		if seasonLength == 0 {
+			return errNoFixtures
+		}
+
+		// The next week is the earliest one with a match left to play, so rescheduled games in hand are caught up first
+		nextWeek = 0
+		postponed := 0
+		for _, m := range matches {
+			if m.Played || m.Week > seasonLength {
+				continue
+			}
+			if m.Postponed {
+				postponed++
+			} else if nextWeek == 0 || m.Week < nextWeek {
+				nextWeek = m.Week
+			}
+		}
+		if nextWeek == 0 && postponed > 0 {
+			return fmt.Errorf("%w: %d postponed matches need a new week", errSeasonNotFinished, postponed)
+		}
+		if nextWeek == 0 {
+			return errSeasonEnded
+		}
+
+		// The engine plays with the ratings after the last played week
+		teamsByID := make(map[int]Team, len(stored))
+		for _, t := range computeStandings(stored, matches, s.settings) {
+			teamsByID[t.ID] = t
+		}
+
+		// For each match simulate the result and update the repository
+		for _, m := range matches {
+			if m.Week != nextWeek || m.Played || m.Postponed {
+				continue
+			}
+			home_goals, away_goals := s.settings.Engine.SimulateMatch(rng, teamsByID[m.HomeTeamID], teamsByID[m.AwayTeamID])
+			if err := repo.SaveMatchResult(m.ID, home_goals, away_goals); err != nil {
+				return err
+			}
+		}
+
+		// Recompute the standings from all played matches and store them on the teams
+		teams, err = syncStandings(repo, s.settings)
+		return err
+	})
+	if err != nil {
+		return 0, nil, err
+	}
 
 	// Return the next week number and the updated standings
-    return nextWeek, teams, nil
+	return nextWeek, teams, nil
 }
 
+// ChangeMatchResult overwrites the score of a match and returns its week with the recomputed standings
+func (s *MyMatchService) ChangeMatchResult(matchID, homeGoals, awayGoals int) (int, []Team, error) {
+	if homeGoals < 0 || awayGoals < 0 {
+		return 0, nil, fmt.Errorf("%w: match %d has negative goals", errInvalidInput, matchID)
+	}
+	var week int
+	var teams []Team
+	err := s.repo.Atomic(func(repo LeagueRepository) error {
+		match, err := repo.Match(matchID)
+		if err != nil {
+			return err
+		}
+		week = match.Week
+
+		if err := repo.SaveMatchResult(matchID, homeGoals, awayGoals); err != nil {
+			return err
+		}
+
+		// The standings are derived from the match results, so there is nothing to revert by hand
+		teams, err = syncStandings(repo, s.settings)
+		return err
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return week, teams, nil
+}
 
-// Reset all matches to their initial state
-func (s *MyMatchService) ResetMatches() error {
-    _, err := s.db.Exec(`
-        UPDATE matches 
-        SET home_goals = NULL,
-            away_goals = NULL,
-            played = FALSE
-    `)
-    return err
+// SeasonLength returns the number of weeks in the current schedule
+func (s *MyMatchService) SeasonLength() (int, error) {
+	matches, err := s.repo.Matches()
+	if err != nil {
+		return 0, err
+	}
+	return s.settings.seasonLength(matches), nil
 }
 
-// probabilities_Message prepares a message with championship probabilities based on the current week
-func (s *MyMatchService) probabilities_Message(teamService *MyTeamService, matchService *MyMatchService, week int) (interface{}, error) {
-	if week <= 3 {
+// GenerateFixtures replaces all matches with a round-robin schedule for the current teams
+func (s *MyMatchService) GenerateFixtures(doubleRoundRobin bool) ([]Match, error) {
+	var matches []Match
+	err := s.repo.Atomic(func(repo LeagueRepository) error {
+		teams, err := repo.Teams()
+		if err != nil {
+			return err
+		}
+		if len(teams) < 2 {
+			return fmt.Errorf("At least two teams are needed to generate fixtures")
+		}
+
+		matches, err = repo.ReplaceMatches(generateRoundRobin(teams, doubleRoundRobin))
+		if err != nil {
+			return err
+		}
+
+		// The stats built on the old schedule are no longer valid
+		_, err = syncStandings(repo, s.settings)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// Settings returns the league settings the service simulates with
+func (s *MyMatchService) Settings() LeagueSettings {
+	return s.settings
+}
+
+// probabilities_Message prepares a message with championship probabilities based on the current week.
+// A positive simulations count overrides the configured number of Monte Carlo runs.
+func (s *MyMatchService) probabilities_Message(teamService TeamService, week int, rng *rand.Rand, simulations int) (interface{}, error) {
+	seasonLength, err := s.SeasonLength()
+	if err != nil {
+		return nil, err
+	}
+	// Probabilities are only meaningful once the first half of the season is over
+	if week <= seasonLength/2 {
 		return "Not enough weeks played to calculate championship probabilities", nil
 	}
-	probabilities, err := SimulateChampionshipProbabilities(teamService, matchService, week)
+	settings := s.settings
+	if simulations > 0 {
+		settings.Simulations = simulations
+	}
+	probabilities, err := SimulateChampionshipProbabilities(teamService, s, settings, rng)
 	if err != nil {
-		return "Could not calculate probabilities: " + err.Error(), nil
+		return nil, fmt.Errorf("could not calculate probabilities: %w", err)
 	}
 	return probabilities, nil
 }
 
+// addProbabilities puts the championship probabilities into a response, or the reason they could not be calculated.
+// The matches are already stored by then, so the request still succeeds.
+func addProbabilities(response gin.H, probabilities interface{}, err error) gin.H {
+	if err != nil {
+		response["probabilities_error"] = err.Error()
+		return response
+	}
+	response["championship_probabilities"] = probabilities
+	return response
+}
 
 // --- Main and Handlers ---
 
-// main function initializes the database connection and sets up the HTTP server
+// main function initializes the storage backend and sets up the HTTP server
 func main() {
-	// Initialize the database connection
-    db, err := sql.Open("mysql", "root:berkemre123@tcp(127.0.0.1:3306)/leaguedb")
-    if err != nil {
-        log.Fatal("DB bağlantısı başarısız:", err)
-    }
-    err = db.Ping()
-    if err != nil {
-        log.Fatal("DB erişimi başarısız:", err)
-    }
-
-	// Initialize services
-    teamService := &MyTeamService{db: db}
-    matchService := &MyMatchService{db: db, teamService: teamService}
-
-	// Initialize Gin router
-    r := gin.Default()
-
-	// Endpoint to get all teams
-    r.GET("/teams", func(c *gin.Context) {
-        teams, err := teamService.GetTeams()
-        if err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-            return
-        }
-        c.JSON(http.StatusOK, teams)
-    })
-
-	// Endpoint to get all matches
-    r.GET("/matches", func(c *gin.Context) {
-        matches, err := matchService.GetMatches()
-        if err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-            return
-        }
-        c.JSON(http.StatusOK, matches)
-    })
+	configPath := flag.String("config", "", "YAML or TOML config file (defaults to $LEAGUE_CONFIG)")
+	storage := flag.String("storage", "", "storage backend: mysql, sqlite or memory (overrides the config)")
+	dsn := flag.String("dsn", "", "MySQL DSN or SQLite file path (overrides the config)")
+	flag.Parse()
+
+	// Load the configuration, command line flags take precedence over the file and the environment
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatal("Config yüklenemedi: ", err)
+	}
+	if *storage != "" {
+		cfg.Database.Driver = *storage
+	}
+	if *dsn != "" {
+		cfg.Database.DSN = *dsn
+	}
+	if err := cfg.validate(); err != nil {
+		log.Fatal("Config geçersiz: ", err)
+	}
+	level, _ := cfg.logLevel()
+	slog.SetLogLoggerLevel(level)
+
+	// Commands given after the flags run instead of the HTTP server
+	switch flag.Arg(0) {
+	case "":
+	case "backtest", "import":
+	default:
+		log.Fatalf("Unknown command %q, expected backtest, import or nothing to start the server", flag.Arg(0))
+	}
+
+	// Initialize the store for the selected backend
+	var store LeagueStore
+	switch cfg.Database.Driver {
+	case "mysql", "sqlite":
+		source := cfg.Database.DSN
+		if cfg.Database.Driver == "sqlite" {
+			source = sqliteDSN(source)
+		}
+
+		db, err := sql.Open(cfg.Database.Driver, source)
+		if err != nil {
+			log.Fatal("DB bağlantısı başarısız:", err)
+		}
+		err = db.Ping()
+		if err != nil {
+			log.Fatal("DB erişimi başarısız:", err)
+		}
+
+		// Create or upgrade the schema before serving requests
+		if err := migrate(db, cfg.Database.Driver); err != nil {
+			log.Fatal("DB migration başarısız:", err)
+		}
+		store = newSQLStore(db, cfg.Database.Driver)
+	case "memory":
+		store = newMemoryStore(sampleTeams())
+	}
+
+	// The fitted engine plays with the last fit applied to it
+	settings := cfg.settings()
+	model, err := store.FittedModel()
+	if err != nil {
+		log.Fatal("Fitted model yüklenemedi: ", err)
+	}
+	settings.Fitted.use(model)
+
+	// Initialize services, team and match services are built per request for the season it is scoped to
+	leagueService := &MyLeagueService{store: store, settings: settings}
+
+	// The backtest command reports on a stored season once the store is ready
+	if flag.Arg(0) == "backtest" {
+		if err := runBacktest(leagueService, flag.Arg(1)); err != nil {
+			log.Fatal("Backtest başarısız: ", err)
+		}
+		return
+	}
+
+	// The import command loads a results file into a new season of a league
+	importService := &MyImportService{store: store, settings: settings}
+	if flag.Arg(0) == "import" {
+		if err := runImport(importService, flag.Args()[1:]); err != nil {
+			log.Fatal("Import başarısız: ", err)
+		}
+		return
+	}
+
+	// Initialize Gin router, request logs are only written at info level and below
+	if level > slog.LevelDebug {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	r := gin.New()
+	if level <= slog.LevelInfo {
+		r.Use(gin.Logger())
+	}
+	r.Use(gin.Recovery())
+
+	// Endpoints to list and create leagues, and to list and start their seasons
+	r.GET("/leagues", ListLeaguesHandler(leagueService))
+	r.POST("/leagues", CreateLeagueHandler(leagueService))
+	r.PUT("/leagues/:league_id", UpdateLeagueHandler(leagueService))
+	r.GET("/leagues/:league_id/seasons", ListSeasonsHandler(leagueService))
+	r.POST("/leagues/:league_id/seasons", StartSeasonHandler(leagueService))
+
+	// Endpoints to create, change and delete the teams shared by every league
+	teamAdminService := &MyTeamAdminService{store: store}
+	r.POST("/teams", CreateTeamHandler(teamAdminService))
+	r.PUT("/teams/:id", UpdateTeamHandler(teamAdminService, false))
+	r.PATCH("/teams/:id", UpdateTeamHandler(teamAdminService, true))
+	r.DELETE("/teams/:id", DeleteTeamHandler(teamAdminService))
+
+	// Endpoint to fit team strengths to played matches, and to apply them to the teams or the fitted engine
+	fitService := &MyStrengthFitService{store: store, engine: settings.Fitted}
+	r.POST("/strengths/fit", FitStrengthsHandler(fitService))
+
+	// Endpoint to import played matches from a CSV results file into a new season, creating the teams it does not know
+	r.POST("/import", ImportHandler(importService))
+
+	// Endpoints to end the season of a league and its linked divisions with promotion and relegation, and start the next one
+	r.POST("/leagues/:league_id/rollover", RolloverHandler(leagueService, 0))
+	r.POST("/rollover", RolloverHandler(leagueService, 1))
+
+	// Every season has its own copy of the league endpoints
+	registerSeasonRoutes(r.Group("/leagues/:league_id/seasons/:season_id"), pathSeasonScope(leagueService))
+
+	// The unscoped endpoints work on the current season of the first league
+	registerSeasonRoutes(r, currentSeasonScope(leagueService, 1))
+
+	r.Run(cfg.ListenAddr)
+}
+
+// registerSeasonRoutes adds the endpoints that work on a single season.
+// League table endpoints are refused in cup and tournament seasons, and the cup and tournament endpoints in the other formats.
+func registerSeasonRoutes(r gin.IRoutes, scope seasonScope) {
+	teams := func(format string, handler func(TeamService) gin.HandlerFunc) gin.HandlerFunc {
+		return inSeason(scope, format, func(s seasonServices) gin.HandlerFunc {
+			return handler(s.teams)
+		})
+	}
+	matches := func(format string, handler func(MatchService) gin.HandlerFunc) gin.HandlerFunc {
+		return inSeason(scope, format, func(s seasonServices) gin.HandlerFunc {
+			return handler(s.matches)
+		})
+	}
+	both := func(format string, handler func(TeamService, MatchService) gin.HandlerFunc) gin.HandlerFunc {
+		return inSeason(scope, format, func(s seasonServices) gin.HandlerFunc {
+			return handler(s.teams, s.matches)
+		})
+	}
+	cup := func(handler func(CupService) gin.HandlerFunc) gin.HandlerFunc {
+		return inSeason(scope, formatCup, func(s seasonServices) gin.HandlerFunc {
+			return handler(s.cup)
+		})
+	}
+	tournament := func(handler func(TournamentService) gin.HandlerFunc) gin.HandlerFunc {
+		return inSeason(scope, formatTournament, func(s seasonServices) gin.HandlerFunc {
+			return handler(s.tournament)
+		})
+	}
+
+	// Endpoints to get all teams and all matches
+	r.GET("/teams", teams("", TeamsHandler))
+	r.GET("/matches", matches("", MatchesHandler))
 
 	// Endpoint to play a single week of matches
-    r.POST("/play-week", func(c *gin.Context) {
-        week, teams, err := matchService.PlayWeek()
-        if err != nil {
-            c.JSON(http.StatusOK, gin.H{"error": err.Error()})
-            return
-        }
-
-		probabilities, err := matchService.probabilities_Message(teamService, matchService, week)
-
-        c.JSON(http.StatusOK, gin.H{
-            "message":   fmt.Sprintf("Week %d played successfully", week),
-            "standings": teams,
-			"championship_probabilities": probabilities,
-        })
-    })
+	r.POST("/play-week", both(formatLeague, PlayWeekHandler))
+
+	// Endpoint for the exact title race: clinched and eliminated teams, magic numbers and the positions still possible
+	r.GET("/title-race", both(formatLeague, TitleRaceHandler))
 
 	// Endpoint to play all weeks until the season ends
-    r.POST("/play-all", func(c *gin.Context) {
-		var results []WeeklyResult
-		weekProbabilities := make(map[int]interface{})
+	r.POST("/play-all", both(formatLeague, func(teamService TeamService, matchService MatchService) gin.HandlerFunc {
+		return PlayAllHandler(matchService, teamService)
+	}))
+
+	// Endpoint to change match result. Then update standings and championship probabilities for that week accordingly.
+	r.POST("/change-match-result", both(formatLeague, ChangeMatchResultHandler))
+
+	// Endpoint to project standings and championship probabilities for hypothetical results, nothing is stored
+	r.POST("/scenarios", both(formatLeague, ScenarioHandler))
+
+	// Endpoint to generate a round-robin schedule for the current teams
+	r.POST("/generate-fixtures", matches(formatLeague, GenerateFixturesHandler))
+
+	// Endpoints to add, move, postpone and cancel single fixtures, postponed matches are games in hand until rescheduled
+	r.POST("/matches", matches(formatLeague, AddFixtureHandler))
+	r.PATCH("/matches/:id", matches(formatLeague, RescheduleMatchHandler))
+	r.POST("/matches/:id/postpone", matches(formatLeague, PostponeMatchHandler))
+	r.DELETE("/matches/:id", matches(formatLeague, CancelMatchHandler))
+
+	// Endpoints for the outcome probabilities, expected goals and likely scores the engine gives unplayed matches
+	r.GET("/matches/:id/prediction", matches("", PredictionHandler))
+	r.GET("/predictions", matches("", PredictionsHandler))
+
+	// Endpoint to score every match engine against the played matches with Brier score, log-loss, calibration and goal counts
+	r.GET("/backtest", matches("", BacktestHandler))
+
+	// Endpoints to rebuild the stored standings and to check them against the match results
+	r.POST("/standings/rebuild", teams("", RebuildStandingsHandler))
+	r.GET("/standings/check", teams("", CheckStandingsHandler))
+
+	// Endpoints for the ledger of administrative points deductions
+	r.GET("/deductions", teams(formatLeague, ListDeductionsHandler))
+	r.POST("/deductions", teams(formatLeague, AddDeductionHandler))
+	r.DELETE("/deductions/:id", teams(formatLeague, DeleteDeductionHandler))
+
+	// Endpoint to set the disciplinary points a team has collected, ranked by the fair_play tiebreaker
+	r.PUT("/fair-play", teams("", FairPlayHandler))
+
+	// Endpoint for the Elo ratings the engines play with, with their history week by week
+	r.GET("/ratings", teams(formatLeague, RatingsHandler))
+
+	// Endpoint for the finishing position probabilities, expected points and zone odds of every team
+	r.GET("/probabilities", both(formatLeague, ProbabilitiesHandler))
+
+	// Endpoints for the knockout bracket of a cup season, to play its next round and for the odds of reaching each round
+	r.GET("/cup/bracket", cup(CupBracketHandler))
+	r.POST("/cup/play-round", cup(PlayCupRoundHandler))
+	r.GET("/cup/probabilities", cup(CupProbabilitiesHandler))
 
-		for {
-			week, standings, err := matchService.PlayWeek()
+	// Endpoints for the groups and knockout of a tournament season, to play its next week or round and for the odds of advancing
+	r.GET("/tournament", tournament(TournamentHandler))
+	r.POST("/tournament/play-round", tournament(PlayTournamentRoundHandler))
+	r.GET("/tournament/probabilities", tournament(TournamentProbabilitiesHandler))
+}
+
+// TeamsHandler handles the request for all teams
+func TeamsHandler(teamService TeamService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		teams, err := teamService.GetTeams()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, teams)
+	}
+}
+
+// MatchesHandler handles the request for all matches
+func MatchesHandler(matchService MatchService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		matches, err := matchService.GetMatches()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, matches)
+	}
+}
+
+// PlayWeekHandler handles the request to play a single week of matches
+func PlayWeekHandler(teamService TeamService, matchService MatchService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		seed, err := requestSeed(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		simulations, err := requestSimulations(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		includeTitleRace, err := requestTitleRace(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		rng := newRNG(seed)
+
+		week, teams, err := matchService.PlayWeek(rng)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		// The week is stored, a failure of the probabilities is reported with the played week
+		probabilities, err := matchService.probabilities_Message(teamService, week, rng, simulations)
+		response := addProbabilities(gin.H{
+			"message":   fmt.Sprintf("Week %d played successfully", week),
+			"standings": teams,
+			"seed":      seed,
+		}, probabilities, err)
+
+		// Exact clinch and elimination analysis, complementing the sampled probabilities, only on request since it can be slow
+		if includeTitleRace {
+			titleRace, err := AnalyzeTitleRace(teamService, matchService, matchService.Settings())
 			if err != nil {
-				if err.Error() == "Season has ended" {
-					break
-				}
 				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 				return
 			}
-
-			// Collect standings for this week
-			results = append(results, WeeklyResult{
-				Week:      week,
-				Standings: standings,
-			})
-
-			// Collect probabilities for this week (after week 3)
-			probs, _ := matchService.probabilities_Message(teamService, matchService, week)
-			weekProbabilities[week] = probs
+			response["title_race"] = titleRace
 		}
 
-		c.JSON(http.StatusOK, gin.H{
-			"message": "All matches played successfully",
-			"weeks":   results,
-			"championship_probabilities": weekProbabilities,
-		})
-	})
+		c.JSON(http.StatusOK, response)
+	}
+}
 
-	// Endpoint to change match result. Then update standings and championship probabilities for that week accordingly.
-	r.POST("/change-match-result", func(c *gin.Context) {
+// ChangeMatchResultHandler handles the request to overwrite the score of a match
+func ChangeMatchResultHandler(teamService TeamService, matchService MatchService) gin.HandlerFunc {
+	return func(c *gin.Context) {
 		type ChangeMatchRequest struct {
-			MatchID    int `json:"match_id"`
-			HomeGoals  int `json:"home_goals"`
-			AwayGoals  int `json:"away_goals"`
+			MatchID   int `json:"match_id"`
+			HomeGoals int `json:"home_goals"`
+			AwayGoals int `json:"away_goals"`
 		}
 		var req ChangeMatchRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 			return
 		}
+		seed, err := requestSeed(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		simulations, err := requestSimulations(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-		teams, probabilities, err := UpdateMatchResult(matchService.db, teamService, matchService, req.MatchID, req.HomeGoals, req.AwayGoals)
+		teams, probabilities, probabilitiesErr, err := UpdateMatchResult(teamService, matchService, req.MatchID, req.HomeGoals, req.AwayGoals, newRNG(seed), simulations)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{
+		c.JSON(http.StatusOK, addProbabilities(gin.H{
 			"message":   "Match result updated successfully",
 			"standings": teams,
-			"championship_probabilities": probabilities,
-		})
-	})
-
-
-	// Endpoint to reset all teams
-    r.POST("/reset-teams", func(c *gin.Context) {
-        if err := teamService.ResetTeams(); err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset teams: " + err.Error()})
-            return
-        }
-        c.JSON(http.StatusOK, gin.H{"message": "Teams reset successfully"})
-    })
-
-	// Endpoint to reset all matches
-    r.POST("/reset-matches", func(c *gin.Context) {
-        if err := matchService.ResetMatches(); err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset matches: " + err.Error()})
-            return
-        }
-        c.JSON(http.StatusOK, gin.H{"message": "Matches reset successfully"})
-    })
-
-    r.Run(":8080")
+			"seed":      seed,
+		}, probabilities, probabilitiesErr))
+	}
 }
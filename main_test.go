@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPlayWeekAnswersConflictOnceTheSeasonIsOver(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	leagueService := &MyLeagueService{store: newMemoryStore(sampleTeams()), settings: benchmarkSettings()}
+	services, err := leagueService.SeasonServices(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	router.POST("/play-week", PlayWeekHandler(services.teams, services.matches))
+	playWeek := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/play-week?seed=1&simulations=10", nil))
+		return w
+	}
+
+	weeks, _ := services.matches.SeasonLength()
+	for week := 1; week <= weeks; week++ {
+		if w := playWeek(); w.Code != http.StatusOK || strings.Contains(w.Body.String(), `"error"`) {
+			t.Fatalf("week %d: status %d: %s", week, w.Code, w.Body.String())
+		}
+	}
+	if w := playWeek(); w.Code != http.StatusConflict {
+		t.Errorf("playing after the last week: status %d, want %d: %s", w.Code, http.StatusConflict, w.Body.String())
+	}
+
+	if _, err := services.matches.repo.ReplaceMatches(nil); err != nil {
+		t.Fatal(err)
+	}
+	if w := playWeek(); w.Code != http.StatusConflict {
+		t.Errorf("playing without fixtures: status %d, want %d: %s", w.Code, http.StatusConflict, w.Body.String())
+	}
+}
+
+// failingTeamService plays the season but cannot read the table the probabilities start from
+type failingTeamService struct {
+	TeamService
+}
+
+func (failingTeamService) GetTeams() ([]Team, error) {
+	return nil, errors.New("table unavailable")
+}
+
+func TestPlayWeekReportsFailedProbabilitiesWithThePlayedWeek(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	leagueService := &MyLeagueService{store: newMemoryStore(sampleTeams()), settings: benchmarkSettings()}
+	services, err := leagueService.SeasonServices(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	router.POST("/play-week", PlayWeekHandler(failingTeamService{services.teams}, services.matches))
+
+	// Probabilities start in the second half of the season
+	weeks, _ := services.matches.SeasonLength()
+	for week := 1; week <= weeks; week++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/play-week?seed=1&simulations=10", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("week %d: status %d: %s", week, w.Code, w.Body.String())
+		}
+		failed := strings.Contains(w.Body.String(), `"probabilities_error":"could not calculate probabilities: table unavailable"`)
+		if failed != (week > weeks/2) {
+			t.Errorf("week %d: probabilities error reported %v: %s", week, failed, w.Body.String())
+		}
+	}
+
+	// Every week was played once, a retry would not have played the next one
+	matches, _ := services.matches.GetMatches()
+	for _, m := range matches {
+		if !m.Played {
+			t.Errorf("match %d of week %d was not played", m.ID, m.Week)
+		}
+	}
+}
+
+func TestChangeMatchResultRejectsNegativeGoals(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	leagueService := &MyLeagueService{store: newMemoryStore(sampleTeams()), settings: benchmarkSettings()}
+	services, err := leagueService.SeasonServices(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	router.POST("/change-match-result", ChangeMatchResultHandler(services.teams, services.matches))
+
+	for _, body := range []string{`{"match_id":1,"home_goals":-5,"away_goals":0}`, `{"match_id":1,"home_goals":0,"away_goals":-1}`} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/change-match-result", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want %d: %s", body, w.Code, http.StatusBadRequest, w.Body.String())
+		}
+	}
+	if match, _ := services.matches.repo.Match(1); match.Played || match.HomeGoals != nil {
+		t.Errorf("a rejected result was stored: %+v", match)
+	}
+}
@@ -0,0 +1,576 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// memoryStore implements LeagueStore without a database, data lives as long as the process
+type memoryStore struct {
+	mu              *sync.RWMutex // nil while running inside Atomic
+	leagues         []League
+	seasons         []Season
+	teams           []Team         // name and strength only
+	seasonTeams     map[int][]Team // counters of every team taking part, by season
+	matches         []Match
+	deductions      []Deduction
+	ratings         map[int][]RatingPoint // rating history by season
+	fitted          FittedModel
+	nextLeagueID    int
+	nextSeasonID    int
+	nextTeamID      int
+	nextMatchID     int
+	nextDeductionID int
+}
+
+// memoryRepository implements LeagueRepository for one season of a memoryStore
+type memoryRepository struct {
+	store    *memoryStore
+	seasonID int
+}
+
+// newMemoryStore creates a store holding one league whose first season is a double round-robin between the given teams
+func newMemoryStore(teams []Team) *memoryStore {
+	s := &memoryStore{
+		mu:              &sync.RWMutex{},
+		seasonTeams:     make(map[int][]Team),
+		ratings:         make(map[int][]RatingPoint),
+		fitted:          FittedModel{Teams: make(map[int]FittedParameters)},
+		nextLeagueID:    1,
+		nextSeasonID:    1,
+		nextTeamID:      1,
+		nextMatchID:     1,
+		nextDeductionID: 1,
+	}
+	for _, t := range teams {
+		s.teams = append(s.teams, Team{ID: t.ID, Name: t.Name, Strength: t.Strength})
+		s.nextTeamID = max(s.nextTeamID, t.ID+1)
+	}
+	league, _ := s.CreateLeague(League{Name: "Premier League", Format: formatLeague, Legs: 1})
+	season, _ := s.CreateSeason(league.ID, "Season 1")
+	entrants := make([]Team, len(teams))
+	for i, t := range teams {
+		entrants[i] = Team{ID: t.ID, Seed: i + 1}
+	}
+	s.AddSeasonTeams(season.ID, entrants)
+	s.ForSeason(season.ID).ReplaceMatches(generateRoundRobin(teams, true))
+	return s
+}
+
+// sampleTeams returns the four teams the league has always been seeded with
+func sampleTeams() []Team {
+	return []Team{
+		{ID: 1, Name: "Manchester United", Strength: 68},
+		{ID: 2, Name: "Liverpool", Strength: 98},
+		{ID: 3, Name: "Leicester City", Strength: 55},
+		{ID: 4, Name: "Manchester City", Strength: 84},
+	}
+}
+
+// rlock takes the read lock unless the store is a working copy inside Atomic
+func (s *memoryStore) rlock() func() {
+	if s.mu == nil {
+		return func() {}
+	}
+	s.mu.RLock()
+	return s.mu.RUnlock
+}
+
+// write runs fn on the working copy of an atomic block
+func (s *memoryStore) write(fn func(w *memoryStore) error) error {
+	return s.Atomic(func(store LeagueStore) error {
+		return fn(store.(*memoryStore))
+	})
+}
+
+// Leagues returns every league ordered by id
+func (s *memoryStore) Leagues() ([]League, error) {
+	defer s.rlock()()
+	return append([]League{}, s.leagues...), nil
+}
+
+// League returns a single league by id
+func (s *memoryStore) League(id int) (League, error) {
+	defer s.rlock()()
+	for _, l := range s.leagues {
+		if l.ID == id {
+			return l, nil
+		}
+	}
+	return League{}, errLeagueNotFound
+}
+
+// LockLeagues has nothing to lock, Atomic already runs one writer at a time
+func (s *memoryStore) LockLeagues(ids ...int) error {
+	return nil
+}
+
+// CreateLeague stores a league, returning it with its new id
+func (s *memoryStore) CreateLeague(l League) (League, error) {
+	err := s.write(func(w *memoryStore) error {
+		l.ID = w.nextLeagueID
+		w.nextLeagueID++
+		w.leagues = append(w.leagues, l)
+		return nil
+	})
+	return l, err
+}
+
+// SaveLeague overwrites the name, the match engine and the division settings of a league, its format is fixed at creation
+func (s *memoryStore) SaveLeague(l League) error {
+	return s.write(func(w *memoryStore) error {
+		for i := range w.leagues {
+			if w.leagues[i].ID == l.ID {
+				l.Format, l.Legs = w.leagues[i].Format, w.leagues[i].Legs
+				l.Groups, l.GroupQualifiers, l.BestPlaced = w.leagues[i].Groups, w.leagues[i].GroupQualifiers, w.leagues[i].BestPlaced
+				w.leagues[i] = l
+				return nil
+			}
+		}
+		return errLeagueNotFound
+	})
+}
+
+// Seasons returns every season of a league, oldest first
+func (s *memoryStore) Seasons(leagueID int) ([]Season, error) {
+	defer s.rlock()()
+	seasons := []Season{}
+	for _, season := range s.seasons {
+		if season.LeagueID == leagueID {
+			seasons = append(seasons, season)
+		}
+	}
+	return seasons, nil
+}
+
+// Season returns a single season by id
+func (s *memoryStore) Season(id int) (Season, error) {
+	defer s.rlock()()
+	for _, season := range s.seasons {
+		if season.ID == id {
+			return season, nil
+		}
+	}
+	return Season{}, errSeasonNotFound
+}
+
+// CreateSeason stores an active season, returning it with its new id
+func (s *memoryStore) CreateSeason(leagueID int, name string) (Season, error) {
+	var season Season
+	err := s.write(func(w *memoryStore) error {
+		season = Season{ID: w.nextSeasonID, LeagueID: leagueID, Name: name, Status: seasonActive}
+		w.nextSeasonID++
+		w.seasons = append(w.seasons, season)
+		return nil
+	})
+	return season, err
+}
+
+// ArchiveSeasons archives every active season of a league
+func (s *memoryStore) ArchiveSeasons(leagueID int) error {
+	return s.write(func(w *memoryStore) error {
+		for i := range w.seasons {
+			if w.seasons[i].LeagueID == leagueID && w.seasons[i].Status == seasonActive {
+				w.seasons[i].Status = seasonArchived
+			}
+		}
+		return nil
+	})
+}
+
+// ArchiveSeason archives a single season
+func (s *memoryStore) ArchiveSeason(id int) error {
+	return s.write(func(w *memoryStore) error {
+		for i := range w.seasons {
+			if w.seasons[i].ID == id {
+				w.seasons[i].Status = seasonArchived
+			}
+		}
+		return nil
+	})
+}
+
+// AddSeasonTeams enters teams into a season with empty counters, keeping their seed and group
+func (s *memoryStore) AddSeasonTeams(seasonID int, teams []Team) error {
+	return s.write(func(w *memoryStore) error {
+		for _, t := range teams {
+			w.seasonTeams[seasonID] = append(w.seasonTeams[seasonID], Team{ID: t.ID, Seed: t.Seed, Group: t.Group})
+		}
+		return nil
+	})
+}
+
+// AllTeams returns every team ordered by id
+func (s *memoryStore) AllTeams() ([]Team, error) {
+	defer s.rlock()()
+	return cloneTeams(s.teams), nil
+}
+
+// CreateTeam stores a team, returning it with its new id
+func (s *memoryStore) CreateTeam(name string, strength int) (Team, error) {
+	var team Team
+	err := s.write(func(w *memoryStore) error {
+		team = Team{ID: w.nextTeamID, Name: name, Strength: strength}
+		w.nextTeamID++
+		w.teams = append(w.teams, team)
+		return nil
+	})
+	return team, err
+}
+
+// SaveTeam overwrites the name and strength of a team and renames it on its matches
+func (s *memoryStore) SaveTeam(t Team) error {
+	return s.write(func(w *memoryStore) error {
+		i := slices.IndexFunc(w.teams, func(other Team) bool { return other.ID == t.ID })
+		if i < 0 {
+			return errTeamNotFound
+		}
+		w.teams[i].Name, w.teams[i].Strength = t.Name, t.Strength
+		for j := range w.matches {
+			if w.matches[j].HomeTeamID == t.ID {
+				w.matches[j].NameHome = t.Name
+			}
+			if w.matches[j].AwayTeamID == t.ID {
+				w.matches[j].NameAway = t.Name
+			}
+		}
+		return nil
+	})
+}
+
+// HasPlayedMatches reports whether a team has played a match in any season
+func (s *memoryStore) HasPlayedMatches(teamID int) (bool, error) {
+	defer s.rlock()()
+	return slices.ContainsFunc(s.matches, func(m Match) bool {
+		return m.Played && (m.HomeTeamID == teamID || m.AwayTeamID == teamID)
+	}), nil
+}
+
+// DeleteTeam removes a team with its season entries, fixtures, deductions, ratings and fitted parameters
+func (s *memoryStore) DeleteTeam(id int) error {
+	return s.write(func(w *memoryStore) error {
+		n := len(w.teams)
+		w.teams = slices.DeleteFunc(w.teams, func(t Team) bool { return t.ID == id })
+		if len(w.teams) == n {
+			return errTeamNotFound
+		}
+		for seasonID, teams := range w.seasonTeams {
+			w.seasonTeams[seasonID] = slices.DeleteFunc(teams, func(t Team) bool { return t.ID == id })
+		}
+		w.matches = slices.DeleteFunc(w.matches, func(m Match) bool { return m.HomeTeamID == id || m.AwayTeamID == id })
+		w.deductions = slices.DeleteFunc(w.deductions, func(d Deduction) bool { return d.TeamID == id })
+		for seasonID, history := range w.ratings {
+			w.ratings[seasonID] = slices.DeleteFunc(slices.Clone(history), func(p RatingPoint) bool { return p.TeamID == id })
+		}
+		w.fitted.Teams = maps.Clone(w.fitted.Teams)
+		delete(w.fitted.Teams, id)
+		return nil
+	})
+}
+
+// FittedModel returns a copy of the model of the fitted engine
+func (s *memoryStore) FittedModel() (FittedModel, error) {
+	defer s.rlock()()
+	m := s.fitted
+	m.Teams = maps.Clone(s.fitted.Teams)
+	return m, nil
+}
+
+// SaveFittedModel replaces the model of the fitted engine
+func (s *memoryStore) SaveFittedModel(m FittedModel) error {
+	return s.write(func(w *memoryStore) error {
+		w.fitted = m
+		w.fitted.Teams = maps.Clone(m.Teams)
+		return nil
+	})
+}
+
+// ForSeason returns the repository of one season of the store
+func (s *memoryStore) ForSeason(seasonID int) LeagueRepository {
+	return &memoryRepository{store: s, seasonID: seasonID}
+}
+
+// Atomic runs fn on a working copy of the data and keeps the copy only if fn succeeds
+func (s *memoryStore) Atomic(fn func(store LeagueStore) error) error {
+	if s.mu == nil {
+		// Already working on a copy
+		return fn(s)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	work := *s
+	work.mu = nil
+	work.leagues = slices.Clone(s.leagues)
+	work.seasons = slices.Clone(s.seasons)
+	work.teams = cloneTeams(s.teams)
+	work.seasonTeams = make(map[int][]Team, len(s.seasonTeams))
+	for id, teams := range s.seasonTeams {
+		work.seasonTeams[id] = cloneTeams(teams)
+	}
+	work.matches = cloneMatches(s.matches)
+	work.deductions = slices.Clone(s.deductions)
+	work.ratings = make(map[int][]RatingPoint, len(s.ratings))
+	for id, history := range s.ratings {
+		work.ratings[id] = history // replaced as a whole, never modified in place
+	}
+	if err := fn(&work); err != nil {
+		return err
+	}
+	work.mu = s.mu
+	*s = work
+	return nil
+}
+
+// Teams returns a copy of the season's teams with their counters and the total of their points deductions
+func (r *memoryRepository) Teams() ([]Team, error) {
+	s := r.store
+	defer s.rlock()()
+	teams := cloneTeams(s.seasonTeams[r.seasonID])
+	for i := range teams {
+		for _, t := range s.teams {
+			if t.ID == teams[i].ID {
+				teams[i].Name, teams[i].Strength = t.Name, t.Strength
+			}
+		}
+		teams[i].PointsDeducted = 0
+		for _, d := range s.deductions {
+			if d.SeasonID == r.seasonID && d.TeamID == teams[i].ID {
+				teams[i].PointsDeducted += d.Points
+			}
+		}
+	}
+	sort.SliceStable(teams, func(i, j int) bool { return teams[i].ID < teams[j].ID })
+	return teams, nil
+}
+
+// Matches returns a copy of every match of the season ordered by week
+func (r *memoryRepository) Matches() ([]Match, error) {
+	s := r.store
+	defer s.rlock()()
+	var matches []Match
+	for _, m := range s.matches {
+		if m.SeasonID == r.seasonID {
+			matches = append(matches, m)
+		}
+	}
+	matches = cloneMatches(matches)
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Week != matches[j].Week {
+			return matches[i].Week < matches[j].Week
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	return matches, nil
+}
+
+// Match returns a single match of the season by id
+func (r *memoryRepository) Match(id int) (Match, error) {
+	s := r.store
+	defer s.rlock()()
+	for _, m := range s.matches {
+		if m.ID == id && m.SeasonID == r.seasonID {
+			return m, nil
+		}
+	}
+	return Match{}, errMatchNotFound
+}
+
+// SaveMatchResult stores the score of a match and marks it as played
+func (r *memoryRepository) SaveMatchResult(id, homeGoals, awayGoals int) error {
+	return r.write(func(w *memoryStore) error {
+		for i := range w.matches {
+			if w.matches[i].ID == id && w.matches[i].SeasonID == r.seasonID {
+				w.matches[i].HomeGoals = &homeGoals
+				w.matches[i].AwayGoals = &awayGoals
+				w.matches[i].Played = true
+				w.matches[i].Postponed = false
+				return nil
+			}
+		}
+		return errMatchNotFound
+	})
+}
+
+// SaveKnockoutResult stores the score of a cup match including extra time and penalties, and marks it as played
+func (r *memoryRepository) SaveKnockoutResult(m Match) error {
+	return r.write(func(w *memoryStore) error {
+		for i := range w.matches {
+			if w.matches[i].ID == m.ID && w.matches[i].SeasonID == r.seasonID {
+				w.matches[i].HomeGoals = m.HomeGoals
+				w.matches[i].AwayGoals = m.AwayGoals
+				w.matches[i].ExtraTime = m.ExtraTime
+				w.matches[i].HomePenalties = m.HomePenalties
+				w.matches[i].AwayPenalties = m.AwayPenalties
+				w.matches[i].Played = true
+				return nil
+			}
+		}
+		return errMatchNotFound
+	})
+}
+
+// ReplaceMatches drops every match of the season and stores the given ones, returning them with their new ids
+func (r *memoryRepository) ReplaceMatches(matches []Match) ([]Match, error) {
+	var inserted []Match
+	err := r.write(func(w *memoryStore) error {
+		w.matches = slices.DeleteFunc(w.matches, func(m Match) bool { return m.SeasonID == r.seasonID })
+		var err error
+		inserted, err = (&memoryRepository{store: w, seasonID: r.seasonID}).AddMatches(matches)
+		return err
+	})
+	return inserted, err
+}
+
+// AddMatches stores the given matches next to the existing ones, returning them with their new ids
+func (r *memoryRepository) AddMatches(matches []Match) ([]Match, error) {
+	var inserted []Match
+	err := r.write(func(w *memoryStore) error {
+		inserted = cloneMatches(matches)
+		for i := range inserted {
+			inserted[i].ID = w.nextMatchID
+			inserted[i].SeasonID = r.seasonID
+			w.nextMatchID++
+		}
+		w.matches = append(w.matches, cloneMatches(inserted)...)
+		return nil
+	})
+	return inserted, err
+}
+
+// RescheduleMatch moves a match of the season to another week or marks it as postponed
+func (r *memoryRepository) RescheduleMatch(id, week int, postponed bool) error {
+	return r.write(func(w *memoryStore) error {
+		for i := range w.matches {
+			if w.matches[i].ID == id && w.matches[i].SeasonID == r.seasonID {
+				w.matches[i].Week, w.matches[i].Postponed = week, postponed
+				return nil
+			}
+		}
+		return errMatchNotFound
+	})
+}
+
+// DeleteMatch removes a match of the season
+func (r *memoryRepository) DeleteMatch(id int) error {
+	return r.write(func(w *memoryStore) error {
+		n := len(w.matches)
+		w.matches = slices.DeleteFunc(w.matches, func(m Match) bool { return m.ID == id && m.SeasonID == r.seasonID })
+		if len(w.matches) == n {
+			return errMatchNotFound
+		}
+		return nil
+	})
+}
+
+// SaveStandings overwrites the stored team counters and fair play points of the season
+func (r *memoryRepository) SaveStandings(teams []Team) error {
+	return r.write(func(w *memoryStore) error {
+		stored := w.seasonTeams[r.seasonID]
+		for _, t := range teams {
+			for i := range stored {
+				if stored[i].ID == t.ID {
+					stored[i].Points = t.Points
+					stored[i].GoalsFor = t.GoalsFor
+					stored[i].GoalsAgainst = t.GoalsAgainst
+					stored[i].GoalDiff = t.GoalDiff
+					stored[i].Wins = t.Wins
+					stored[i].Draws = t.Draws
+					stored[i].Losses = t.Losses
+					stored[i].FairPlayPoints = t.FairPlayPoints
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Ratings returns a copy of the season's rating history ordered by team and week
+func (r *memoryRepository) Ratings() ([]RatingPoint, error) {
+	s := r.store
+	defer s.rlock()()
+	history := append([]RatingPoint{}, s.ratings[r.seasonID]...)
+	sort.SliceStable(history, func(i, j int) bool {
+		if history[i].TeamID != history[j].TeamID {
+			return history[i].TeamID < history[j].TeamID
+		}
+		return history[i].Week < history[j].Week
+	})
+	return history, nil
+}
+
+// SaveRatings replaces the rating history of the season
+func (r *memoryRepository) SaveRatings(history []RatingPoint) error {
+	return r.write(func(w *memoryStore) error {
+		w.ratings[r.seasonID] = slices.Clone(history)
+		return nil
+	})
+}
+
+// Deductions returns a copy of the season's points deductions ledger ordered by date
+func (r *memoryRepository) Deductions() ([]Deduction, error) {
+	s := r.store
+	defer s.rlock()()
+	deductions := []Deduction{}
+	for _, d := range s.deductions {
+		if d.SeasonID == r.seasonID {
+			deductions = append(deductions, d)
+		}
+	}
+	sort.SliceStable(deductions, func(i, j int) bool {
+		if deductions[i].Date != deductions[j].Date {
+			return deductions[i].Date < deductions[j].Date
+		}
+		return deductions[i].ID < deductions[j].ID
+	})
+	return deductions, nil
+}
+
+// AddDeduction stores a points deduction, returning it with its new id
+func (r *memoryRepository) AddDeduction(d Deduction) (Deduction, error) {
+	err := r.write(func(w *memoryStore) error {
+		d.ID = w.nextDeductionID
+		d.SeasonID = r.seasonID
+		w.nextDeductionID++
+		w.deductions = append(w.deductions, d)
+		return nil
+	})
+	return d, err
+}
+
+// DeleteDeduction removes a points deduction of the season
+func (r *memoryRepository) DeleteDeduction(id int) error {
+	return r.write(func(w *memoryStore) error {
+		for i, d := range w.deductions {
+			if d.ID == id && d.SeasonID == r.seasonID {
+				w.deductions = slices.Delete(w.deductions, i, i+1)
+				return nil
+			}
+		}
+		return errDeductionNotFound
+	})
+}
+
+// write runs fn on the working copy of the store inside the season's atomic block
+func (r *memoryRepository) write(fn func(w *memoryStore) error) error {
+	return r.Atomic(func(repo LeagueRepository) error {
+		return fn(repo.(*memoryRepository).store)
+	})
+}
+
+// Atomic runs fn on a working copy of the store, as long as the season is not archived
+func (r *memoryRepository) Atomic(fn func(repo LeagueRepository) error) error {
+	return r.store.Atomic(func(store LeagueStore) error {
+		season, err := store.Season(r.seasonID)
+		if err != nil {
+			return err
+		}
+		if season.Status == seasonArchived {
+			return errSeasonArchived
+		}
+		return fn(&memoryRepository{store: store.(*memoryStore), seasonID: r.seasonID})
+	})
+}
@@ -0,0 +1,30 @@
+-- Insert sample teams, unless the database was already set up by hand
+INSERT INTO teams (id, name, strength)
+SELECT seed.id, seed.name, seed.strength
+FROM (
+    SELECT 1 AS id, 'Manchester United' AS name, 68 AS strength
+    UNION ALL SELECT 2, 'Liverpool', 98
+    UNION ALL SELECT 3, 'Leicester City', 55
+    UNION ALL SELECT 4, 'Manchester City', 84
+) AS seed
+WHERE NOT EXISTS (SELECT 1 FROM teams);
+
+-- Insert the double round-robin between the sample teams
+INSERT INTO matches (name_home, name_away, home_team_id, away_team_id, week, played)
+SELECT seed.name_home, seed.name_away, seed.home_team_id, seed.away_team_id, seed.week, FALSE
+FROM (
+    SELECT 'Manchester United' AS name_home, 'Liverpool' AS name_away, 1 AS home_team_id, 2 AS away_team_id, 1 AS week
+    UNION ALL SELECT 'Leicester City', 'Manchester City', 3, 4, 1
+    UNION ALL SELECT 'Manchester United', 'Leicester City', 1, 3, 2
+    UNION ALL SELECT 'Liverpool', 'Manchester City', 2, 4, 2
+    UNION ALL SELECT 'Manchester United', 'Manchester City', 1, 4, 3
+    UNION ALL SELECT 'Liverpool', 'Leicester City', 2, 3, 3
+    UNION ALL SELECT 'Liverpool', 'Manchester United', 2, 1, 4
+    UNION ALL SELECT 'Manchester City', 'Leicester City', 4, 3, 4
+    UNION ALL SELECT 'Leicester City', 'Manchester United', 3, 1, 5
+    UNION ALL SELECT 'Manchester City', 'Liverpool', 4, 2, 5
+    UNION ALL SELECT 'Manchester City', 'Manchester United', 4, 1, 6
+    UNION ALL SELECT 'Leicester City', 'Liverpool', 3, 2, 6
+) AS seed
+WHERE NOT EXISTS (SELECT 1 FROM matches)
+  AND (SELECT COUNT(*) FROM teams WHERE id IN (1, 2, 3, 4)) = 4;
@@ -0,0 +1,9 @@
+-- Ledger of administrative points deductions, the standings subtract the total per team
+CREATE TABLE IF NOT EXISTS point_deductions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    team_id INT NOT NULL,
+    points INT NOT NULL,
+    reason VARCHAR(255) NOT NULL,
+    deducted_on DATE NOT NULL,
+    FOREIGN KEY (team_id) REFERENCES teams(id)
+);
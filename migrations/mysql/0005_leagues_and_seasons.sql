@@ -0,0 +1,52 @@
+-- Teams exist once, their counters, matches and deductions belong to a season of a league.
+-- The existing data becomes season 1 of league 1.
+CREATE TABLE IF NOT EXISTS leagues (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS seasons (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    league_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    FOREIGN KEY (league_id) REFERENCES leagues(id)
+);
+
+CREATE TABLE IF NOT EXISTS season_teams (
+    season_id INT NOT NULL,
+    team_id INT NOT NULL,
+    points INT NOT NULL DEFAULT 0,
+    goals_for INT NOT NULL DEFAULT 0,
+    goals_against INT NOT NULL DEFAULT 0,
+    goal_diff INT NOT NULL DEFAULT 0,
+    wins INT NOT NULL DEFAULT 0,
+    draws INT NOT NULL DEFAULT 0,
+    losses INT NOT NULL DEFAULT 0,
+    fair_play_points INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (season_id, team_id),
+    FOREIGN KEY (season_id) REFERENCES seasons(id),
+    FOREIGN KEY (team_id) REFERENCES teams(id)
+);
+
+INSERT INTO leagues (id, name) VALUES (1, 'Premier League');
+INSERT INTO seasons (id, league_id, name, status) VALUES (1, 1, 'Season 1', 'active');
+INSERT INTO season_teams (season_id, team_id, points, goals_for, goals_against, goal_diff, wins, draws, losses, fair_play_points)
+SELECT 1, id, COALESCE(points, 0), COALESCE(goals_for, 0), COALESCE(goals_against, 0), COALESCE(goal_diff, 0),
+       COALESCE(wins, 0), COALESCE(draws, 0), COALESCE(losses, 0), fair_play_points
+FROM teams;
+
+ALTER TABLE matches ADD COLUMN season_id INT NOT NULL DEFAULT 1;
+CREATE INDEX idx_matches_season ON matches (season_id);
+ALTER TABLE point_deductions ADD COLUMN season_id INT NOT NULL DEFAULT 1;
+CREATE INDEX idx_point_deductions_season ON point_deductions (season_id);
+
+ALTER TABLE teams
+    DROP COLUMN points,
+    DROP COLUMN goals_for,
+    DROP COLUMN goals_against,
+    DROP COLUMN goal_diff,
+    DROP COLUMN wins,
+    DROP COLUMN draws,
+    DROP COLUMN losses,
+    DROP COLUMN fair_play_points;
@@ -0,0 +1,6 @@
+-- A league can sit above another division and exchange teams with it when the season rolls over.
+ALTER TABLE leagues
+    ADD COLUMN division_below_id INT NULL,
+    ADD COLUMN promotion_places INT NOT NULL DEFAULT 0,
+    ADD COLUMN playoff_places INT NOT NULL DEFAULT 0,
+    ADD FOREIGN KEY (division_below_id) REFERENCES leagues(id);
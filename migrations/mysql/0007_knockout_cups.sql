@@ -0,0 +1,14 @@
+-- A league can be played as a knockout cup, its ties are stored as matches with their round, leg and bracket position.
+ALTER TABLE leagues
+    ADD COLUMN format VARCHAR(10) NOT NULL DEFAULT 'league',
+    ADD COLUMN legs INT NOT NULL DEFAULT 1;
+
+ALTER TABLE season_teams ADD COLUMN seed INT NOT NULL DEFAULT 0;
+
+ALTER TABLE matches
+    ADD COLUMN round INT NOT NULL DEFAULT 0,
+    ADD COLUMN leg INT NOT NULL DEFAULT 0,
+    ADD COLUMN tie INT NOT NULL DEFAULT 0,
+    ADD COLUMN extra_time BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN home_penalties INT NULL,
+    ADD COLUMN away_penalties INT NULL;
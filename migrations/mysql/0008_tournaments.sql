@@ -0,0 +1,7 @@
+-- A league can be played as a tournament: a group stage drawn into groups followed by a knockout of the best teams.
+ALTER TABLE leagues
+    ADD COLUMN group_count INT NOT NULL DEFAULT 0,
+    ADD COLUMN group_qualifiers INT NOT NULL DEFAULT 0,
+    ADD COLUMN best_placed INT NOT NULL DEFAULT 0;
+
+ALTER TABLE season_teams ADD COLUMN group_no INT NOT NULL DEFAULT 0;
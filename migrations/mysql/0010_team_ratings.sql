@@ -0,0 +1,10 @@
+-- Elo rating of every team at the end of every week it played, replayed from the matches whenever a result is written
+CREATE TABLE IF NOT EXISTS team_ratings (
+    season_id INT NOT NULL,
+    team_id INT NOT NULL,
+    week INT NOT NULL,
+    rating DOUBLE NOT NULL,
+    PRIMARY KEY (season_id, team_id, week),
+    FOREIGN KEY (season_id) REFERENCES seasons(id),
+    FOREIGN KEY (team_id) REFERENCES teams(id)
+);
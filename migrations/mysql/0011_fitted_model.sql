@@ -0,0 +1,13 @@
+-- Parameters of the fitted match engine, estimated from played matches by POST /strengths/fit
+CREATE TABLE IF NOT EXISTS fitted_model (
+    id INT PRIMARY KEY,
+    home_advantage DOUBLE NOT NULL,
+    base_goals DOUBLE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS fitted_teams (
+    team_id INT PRIMARY KEY,
+    attack DOUBLE NOT NULL,
+    defence DOUBLE NOT NULL,
+    FOREIGN KEY (team_id) REFERENCES teams(id)
+);
@@ -0,0 +1,2 @@
+-- The match engine every season of a league is played with, empty rows follow the configured match_engine.
+ALTER TABLE leagues ADD COLUMN engine VARCHAR(20) NOT NULL DEFAULT '';
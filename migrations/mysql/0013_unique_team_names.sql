@@ -0,0 +1,2 @@
+-- Team names are unique ignoring case (the default collation is case-insensitive), so concurrent creates and renames cannot both win.
+CREATE UNIQUE INDEX ux_teams_name ON teams (name);
@@ -0,0 +1,27 @@
+-- Create teams table
+CREATE TABLE IF NOT EXISTS teams (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(50) NOT NULL,
+    strength INT NOT NULL,
+
+    points INT DEFAULT 0,
+    goals_for INT DEFAULT 0,
+    goals_against INT DEFAULT 0,
+    goal_diff INT DEFAULT 0,
+    wins INT DEFAULT 0,
+    draws INT DEFAULT 0,
+    losses INT DEFAULT 0
+);
+
+-- Create matches table
+CREATE TABLE IF NOT EXISTS matches (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name_home VARCHAR(50) NOT NULL,
+    name_away VARCHAR(50) NOT NULL,
+    home_team_id INT NOT NULL REFERENCES teams(id),
+    away_team_id INT NOT NULL REFERENCES teams(id),
+    home_goals INT,
+    away_goals INT,
+    week INT,
+    played BOOLEAN DEFAULT FALSE
+);
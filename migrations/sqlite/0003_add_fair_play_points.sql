@@ -0,0 +1,2 @@
+-- Disciplinary points used by the fair_play tiebreaker, fewer is better
+ALTER TABLE teams ADD COLUMN fair_play_points INT NOT NULL DEFAULT 0;
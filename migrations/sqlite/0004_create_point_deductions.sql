@@ -0,0 +1,8 @@
+-- Ledger of administrative points deductions, the standings subtract the total per team
+CREATE TABLE IF NOT EXISTS point_deductions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    team_id INT NOT NULL REFERENCES teams(id),
+    points INT NOT NULL,
+    reason VARCHAR(255) NOT NULL,
+    deducted_on VARCHAR(10) NOT NULL -- YYYY-MM-DD, kept as text so the driver does not turn it into a timestamp
+);
@@ -0,0 +1,49 @@
+-- Teams exist once, their counters, matches and deductions belong to a season of a league.
+-- The existing data becomes season 1 of league 1.
+CREATE TABLE IF NOT EXISTS leagues (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(100) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS seasons (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    league_id INT NOT NULL REFERENCES leagues(id),
+    name VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active'
+);
+
+CREATE TABLE IF NOT EXISTS season_teams (
+    season_id INT NOT NULL REFERENCES seasons(id),
+    team_id INT NOT NULL REFERENCES teams(id),
+    points INT NOT NULL DEFAULT 0,
+    goals_for INT NOT NULL DEFAULT 0,
+    goals_against INT NOT NULL DEFAULT 0,
+    goal_diff INT NOT NULL DEFAULT 0,
+    wins INT NOT NULL DEFAULT 0,
+    draws INT NOT NULL DEFAULT 0,
+    losses INT NOT NULL DEFAULT 0,
+    fair_play_points INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (season_id, team_id)
+);
+
+INSERT INTO leagues (id, name) VALUES (1, 'Premier League');
+INSERT INTO seasons (id, league_id, name, status) VALUES (1, 1, 'Season 1', 'active');
+INSERT INTO season_teams (season_id, team_id, points, goals_for, goals_against, goal_diff, wins, draws, losses, fair_play_points)
+SELECT 1, id, COALESCE(points, 0), COALESCE(goals_for, 0), COALESCE(goals_against, 0), COALESCE(goal_diff, 0),
+       COALESCE(wins, 0), COALESCE(draws, 0), COALESCE(losses, 0), fair_play_points
+FROM teams;
+
+ALTER TABLE matches ADD COLUMN season_id INT NOT NULL DEFAULT 1;
+CREATE INDEX idx_matches_season ON matches (season_id);
+ALTER TABLE point_deductions ADD COLUMN season_id INT NOT NULL DEFAULT 1;
+CREATE INDEX idx_point_deductions_season ON point_deductions (season_id);
+
+-- SQLite drops one column per statement
+ALTER TABLE teams DROP COLUMN points;
+ALTER TABLE teams DROP COLUMN goals_for;
+ALTER TABLE teams DROP COLUMN goals_against;
+ALTER TABLE teams DROP COLUMN goal_diff;
+ALTER TABLE teams DROP COLUMN wins;
+ALTER TABLE teams DROP COLUMN draws;
+ALTER TABLE teams DROP COLUMN losses;
+ALTER TABLE teams DROP COLUMN fair_play_points;
@@ -0,0 +1,4 @@
+-- A league can sit above another division and exchange teams with it when the season rolls over.
+ALTER TABLE leagues ADD COLUMN division_below_id INT NULL REFERENCES leagues(id);
+ALTER TABLE leagues ADD COLUMN promotion_places INT NOT NULL DEFAULT 0;
+ALTER TABLE leagues ADD COLUMN playoff_places INT NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- A postponed match keeps its original week until it is rescheduled, it is not played with its week.
+ALTER TABLE matches ADD COLUMN postponed BOOLEAN NOT NULL DEFAULT FALSE;
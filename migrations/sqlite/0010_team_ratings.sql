@@ -0,0 +1,8 @@
+-- Elo rating of every team at the end of every week it played, replayed from the matches whenever a result is written
+CREATE TABLE IF NOT EXISTS team_ratings (
+    season_id INT NOT NULL REFERENCES seasons(id),
+    team_id INT NOT NULL REFERENCES teams(id),
+    week INT NOT NULL,
+    rating REAL NOT NULL,
+    PRIMARY KEY (season_id, team_id, week)
+);
@@ -0,0 +1,12 @@
+-- Parameters of the fitted match engine, estimated from played matches by POST /strengths/fit
+CREATE TABLE IF NOT EXISTS fitted_model (
+    id INT PRIMARY KEY,
+    home_advantage REAL NOT NULL,
+    base_goals REAL NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS fitted_teams (
+    team_id INT PRIMARY KEY REFERENCES teams(id),
+    attack REAL NOT NULL,
+    defence REAL NOT NULL
+);
@@ -0,0 +1,2 @@
+-- Team names are unique ignoring case, so concurrent creates and renames cannot both win.
+CREATE UNIQUE INDEX ux_teams_name ON teams (name COLLATE NOCASE);
@@ -1,44 +1,79 @@
 package main
 
 import (
-    "net/http"
+	"errors"
+	"net/http"
 
-    "github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin"
 )
 
 // PlayAllHandler handles the request to play all matches in the season
 func PlayAllHandler(matchService MatchService, teamService TeamService) gin.HandlerFunc {
-    return func(c *gin.Context) {
-        var results []WeeklyResult
-        weekProbabilities := make(map[int]interface{})
+	return func(c *gin.Context) {
+		// One seed drives every week and every probability run, so the whole season can be replayed
+		seed, err := requestSeed(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		simulations, err := requestSimulations(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		rng := newRNG(seed)
+
+		var results []WeeklyResult
+		weekProbabilities := make(map[int]interface{})
+		probabilitiesErrors := make(map[int]string)
+		var pending string
 
 		// play all matches in the season
-        for {
-            week, standings, err := matchService.PlayWeek()
-            if err != nil {
-                if err.Error() == "Season has ended" {
-                    break
-                }
-                c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-                return
-            }
-
-            // Collect standings for this week
-            results = append(results, WeeklyResult{
-                Week:      week,
-                Standings: standings,
-            })
-
-            // Collect probabilities for this week (after week 3)
-            probs, _ := matchService.probabilities_Message(teamService.(*MyTeamService), matchService.(*MyMatchService), week)
-            weekProbabilities[week] = probs
-        }
+		for {
+			week, standings, err := matchService.PlayWeek(rng)
+			if err != nil {
+				if errors.Is(err, errSeasonEnded) {
+					break
+				}
+				// Postponed matches without a new week are left for later
+				if errors.Is(err, errSeasonNotFinished) {
+					pending = err.Error()
+					break
+				}
+				c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+
+			// Collect standings for this week
+			results = append(results, WeeklyResult{
+				Week:      week,
+				Standings: standings,
+			})
+
+			// Collect probabilities for this week (second half of the season)
+			// The week is stored, a failure of its probabilities is reported with the played weeks
+			probs, err := matchService.probabilities_Message(teamService, week, rng, simulations)
+			if err != nil {
+				probabilitiesErrors[week] = err.Error()
+				continue
+			}
+			weekProbabilities[week] = probs
+		}
 
 		// Respond with the results of all matches played
-        c.JSON(http.StatusOK, gin.H{
-            "message": "All matches played successfully",
-            "weeks":   results,
-            "championship_probabilities": weekProbabilities,
-        })
-    }
-}
\ No newline at end of file
+		response := gin.H{
+			"message":                    "All matches played successfully",
+			"weeks":                      results,
+			"championship_probabilities": weekProbabilities,
+			"seed":                       seed,
+		}
+		if len(probabilitiesErrors) > 0 {
+			response["probabilities_errors"] = probabilitiesErrors
+		}
+		if pending != "" {
+			response["message"] = "All scheduled matches played, postponed matches remain"
+			response["postponed"] = pending
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// PointsSystem is the number of points awarded for each result, plus optional bonus points
+type PointsSystem struct {
+	Win   int         `yaml:"win" toml:"win"`
+	Draw  int         `yaml:"draw" toml:"draw"`
+	Loss  int         `yaml:"loss" toml:"loss"`
+	Bonus []BonusRule `yaml:"bonus" toml:"bonus"`
+}
+
+// BonusRule awards extra points for a single match on top of the result
+type BonusRule struct {
+	Type      string `yaml:"type" toml:"type"`           // goals_scored, win_margin, loss_margin or clean_sheet
+	Threshold int    `yaml:"threshold" toml:"threshold"` // goals or goal margin, unused by clean_sheet
+	Points    int    `yaml:"points" toml:"points"`
+}
+
+// defaultPoints is the usual football scoring of 3 points for a win and 1 for a draw
+var defaultPoints = PointsSystem{Win: 3, Draw: 1}
+
+// applies reports whether a team earns the bonus with the given score, seen from its own side
+func (b BonusRule) applies(goalsFor, goalsAgainst int) bool {
+	switch b.Type {
+	case "goals_scored":
+		return goalsFor >= b.Threshold
+	case "win_margin":
+		return goalsFor-goalsAgainst >= b.Threshold && goalsFor > goalsAgainst
+	case "loss_margin":
+		return goalsAgainst-goalsFor <= b.Threshold && goalsFor < goalsAgainst
+	case "clean_sheet":
+		return goalsAgainst == 0
+	}
+	return false
+}
+
+// pointsFor returns the points a team earns from one match
+func (p PointsSystem) pointsFor(goalsFor, goalsAgainst int) int {
+	points := p.Loss
+	if goalsFor > goalsAgainst {
+		points = p.Win
+	} else if goalsFor == goalsAgainst {
+		points = p.Draw
+	}
+	for _, b := range p.Bonus {
+		if b.applies(goalsFor, goalsAgainst) {
+			points += b.Points
+		}
+	}
+	return points
+}
+
+// outcomes returns every distinct pair of home and away points a single match can award.
+// Bonus rules only compare goals with thresholds, so scores up to twice the largest threshold cover every pair.
+func (p PointsSystem) outcomes() [][2]int {
+	maxGoals := 2
+	for _, b := range p.Bonus {
+		maxGoals = max(maxGoals, 2*b.Threshold+2)
+	}
+	var pairs [][2]int
+	seen := make(map[[2]int]bool)
+	for home := 0; home <= maxGoals; home++ {
+		for away := 0; away <= maxGoals; away++ {
+			pair := [2]int{p.pointsFor(home, away), p.pointsFor(away, home)}
+			if !seen[pair] {
+				seen[pair] = true
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+	return pairs
+}
+
+// validate checks that results are ordered and every bonus rule is known
+func (p PointsSystem) validate() error {
+	var errs []error
+	if p.Loss < 0 || p.Draw < p.Loss || p.Win < p.Draw {
+		errs = append(errs, fmt.Errorf("points must satisfy win >= draw >= loss >= 0, got win=%d draw=%d loss=%d", p.Win, p.Draw, p.Loss))
+	}
+	for i, b := range p.Bonus {
+		switch b.Type {
+		case "goals_scored", "win_margin", "loss_margin", "clean_sheet":
+		default:
+			errs = append(errs, fmt.Errorf("bonus %d must be goals_scored, win_margin, loss_margin or clean_sheet, got %q", i+1, b.Type))
+		}
+		if b.Points < 1 {
+			errs = append(errs, fmt.Errorf("bonus %d must award at least one point, got %d", i+1, b.Points))
+		}
+		if b.Threshold < 0 {
+			errs = append(errs, fmt.Errorf("bonus %d threshold must not be negative, got %d", i+1, b.Threshold))
+		}
+	}
+	return errors.Join(errs...)
+}
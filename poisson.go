@@ -0,0 +1,121 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+)
+
+// League-wide scoring averages the Poisson based engines are calibrated on (Premier League)
+const (
+	averageHomeGoals = 1.6
+	averageAwayGoals = 1.2
+)
+
+// defaultDixonColesRho is the low-score dependence used by the Dixon-Coles engine.
+// Negative values make 0-0 and 1-1 more likely and 1-0 and 0-1 less likely than independent Poisson.
+const defaultDixonColesRho = -0.1
+
+// poissonEngine draws both teams' goals from independent Poisson distributions
+type poissonEngine struct{}
+
+// Name identifies the engine in the configuration
+func (poissonEngine) Name() string { return "poisson" }
+
+// ExpectedGoals scales the league averages by each team's share of the combined strength.
+// Two equal teams get exactly the league averages.
+func (poissonEngine) ExpectedGoals(home, away Team) (float64, float64) {
+	total := home.power() + away.power()
+	return 2 * averageHomeGoals * home.power() / total,
+		2 * averageAwayGoals * away.power() / total
+}
+
+// SimulateMatch samples each side independently
+func (e poissonEngine) SimulateMatch(rng *rand.Rand, home, away Team) (int, int) {
+	lambda, mu := e.ExpectedGoals(home, away)
+	return samplePoisson(rng, lambda), samplePoisson(rng, mu)
+}
+
+// ScoreProbabilities is the product of the two Poisson distributions
+func (e poissonEngine) ScoreProbabilities(home, away Team) [][]float64 {
+	lambda, mu := e.ExpectedGoals(home, away)
+	return outerProduct(poissonDistribution(lambda), poissonDistribution(mu))
+}
+
+// dixonColesEngine corrects the independent Poisson model for the dependence between low scores
+type dixonColesEngine struct {
+	rho float64
+}
+
+// Name identifies the engine in the configuration
+func (dixonColesEngine) Name() string { return "dixon-coles" }
+
+// ExpectedGoals uses the same rates as the Poisson engine
+func (dixonColesEngine) ExpectedGoals(home, away Team) (float64, float64) {
+	return poissonEngine{}.ExpectedGoals(home, away)
+}
+
+// SimulateMatch samples independent Poisson scores and accepts them with probability tau/max(tau),
+// which is exact rejection sampling from the Dixon-Coles distribution
+func (e dixonColesEngine) SimulateMatch(rng *rand.Rand, home, away Team) (int, int) {
+	lambda, mu := e.ExpectedGoals(home, away)
+	maxTau := math.Max(1, math.Max(
+		math.Max(e.tau(0, 0, lambda, mu), e.tau(0, 1, lambda, mu)),
+		math.Max(e.tau(1, 0, lambda, mu), e.tau(1, 1, lambda, mu)),
+	))
+	for {
+		homeGoals, awayGoals := samplePoisson(rng, lambda), samplePoisson(rng, mu)
+		if rng.Float64()*maxTau < e.tau(homeGoals, awayGoals, lambda, mu) {
+			return homeGoals, awayGoals
+		}
+	}
+}
+
+// ScoreProbabilities applies the tau correction to the independent Poisson matrix
+func (e dixonColesEngine) ScoreProbabilities(home, away Team) [][]float64 {
+	lambda, mu := e.ExpectedGoals(home, away)
+	matrix := outerProduct(poissonDistribution(lambda), poissonDistribution(mu))
+	for i := 0; i <= 1; i++ {
+		for j := 0; j <= 1; j++ {
+			matrix[i][j] *= e.tau(i, j, lambda, mu)
+		}
+	}
+	return matrix
+}
+
+// tau is the Dixon-Coles adjustment factor, 1 for every score other than 0-0, 0-1, 1-0 and 1-1
+func (e dixonColesEngine) tau(homeGoals, awayGoals int, lambda, mu float64) float64 {
+	switch {
+	case homeGoals == 0 && awayGoals == 0:
+		return 1 - lambda*mu*e.rho
+	case homeGoals == 0 && awayGoals == 1:
+		return 1 + lambda*e.rho
+	case homeGoals == 1 && awayGoals == 0:
+		return 1 + mu*e.rho
+	case homeGoals == 1 && awayGoals == 1:
+		return 1 - e.rho
+	}
+	return 1
+}
+
+// samplePoisson draws a Poisson distributed number with Knuth's multiplication method
+func samplePoisson(rng *rand.Rand, lambda float64) int {
+	limit := math.Exp(-lambda)
+	k := 0
+	p := rng.Float64()
+	for p > limit {
+		k++
+		p *= rng.Float64()
+	}
+	return k
+}
+
+// poissonDistribution returns P(X = k) for k <= maxMatrixGoals
+func poissonDistribution(lambda float64) []float64 {
+	dist := make([]float64, maxMatrixGoals+1)
+	p := math.Exp(-lambda)
+	for k := range dist {
+		dist[k] = p
+		p *= lambda / float64(k+1)
+	}
+	return dist
+}
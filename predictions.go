@@ -0,0 +1,195 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// predictedScorelines is the number of most likely scorelines returned with a prediction
+const predictedScorelines = 5
+
+// MatchPrediction is how the match engine sees an unplayed match, probabilities are percentages
+type MatchPrediction struct {
+	MatchID           int                   `json:"match_id"`
+	Week              int                   `json:"week"`
+	HomeTeamID        int                   `json:"home_team_id"`
+	AwayTeamID        int                   `json:"away_team_id"`
+	NameHome          string                `json:"name_home"`
+	NameAway          string                `json:"name_away"`
+	Postponed         bool                  `json:"postponed,omitempty"`
+	HomeWin           float64               `json:"home_win"`
+	Draw              float64               `json:"draw"`
+	AwayWin           float64               `json:"away_win"`
+	ExpectedHomeGoals float64               `json:"expected_home_goals"`
+	ExpectedAwayGoals float64               `json:"expected_away_goals"`
+	Scorelines        []ScorelinePrediction `json:"scorelines"`
+}
+
+// ScorelinePrediction is the probability of one exact score
+type ScorelinePrediction struct {
+	HomeGoals   int     `json:"home_goals"`
+	AwayGoals   int     `json:"away_goals"`
+	Probability float64 `json:"probability"`
+}
+
+// Prediction returns the prediction of a single unplayed match
+func (s *MyMatchService) Prediction(id int) (MatchPrediction, error) {
+	match, err := s.repo.Match(id)
+	if err != nil {
+		return MatchPrediction{}, err
+	}
+	if match.Played {
+		return MatchPrediction{}, fmt.Errorf("%w: match %d has already been played", errInvalidInput, id)
+	}
+	predictions, err := s.predict([]Match{match})
+	if err != nil {
+		return MatchPrediction{}, err
+	}
+	return predictions[0], nil
+}
+
+// Predictions returns the predictions of every unplayed match of a week, 0 is the next week to be played
+func (s *MyMatchService) Predictions(week int) (int, []MatchPrediction, error) {
+	matches, err := s.repo.Matches()
+	if err != nil {
+		return 0, nil, err
+	}
+	if week == 0 {
+		for _, m := range matches {
+			if !m.Played && !m.Postponed && (week == 0 || m.Week < week) {
+				week = m.Week
+			}
+		}
+	}
+
+	var unplayed []Match
+	for _, m := range matches {
+		if m.Week == week && !m.Played {
+			unplayed = append(unplayed, m)
+		}
+	}
+	predictions, err := s.predict(unplayed)
+	return week, predictions, err
+}
+
+// predict asks the engine about each match, with the teams as they stand today so that ratings are up to date
+func (s *MyMatchService) predict(matches []Match) ([]MatchPrediction, error) {
+	stored, err := s.repo.Teams()
+	if err != nil {
+		return nil, err
+	}
+	all, err := s.repo.Matches()
+	if err != nil {
+		return nil, err
+	}
+	byID := teamsByID(computeStandings(stored, all, s.settings))
+
+	predictions := []MatchPrediction{}
+	for _, m := range matches {
+		predictions = append(predictions, predictMatch(s.settings.Engine, m, byID[m.HomeTeamID], byID[m.AwayTeamID]))
+	}
+	return predictions, nil
+}
+
+// predictMatch sums the engine's scoreline matrix into the three outcomes and the mean goals of each side,
+// and picks the most likely scores. The matrix stops at maxMatrixGoals per side, so it is rescaled to add up to one.
+// The means are taken from the matrix rather than ExpectedGoals, the legacy engine does not score its expected goals on average.
+func predictMatch(engine MatchEngine, m Match, home, away Team) MatchPrediction {
+	matrix := engine.ScoreProbabilities(home, away)
+
+	var homeWin, draw, awayWin, expectedHome, expectedAway float64
+	var scorelines []ScorelinePrediction
+	for i, row := range matrix {
+		for j, p := range row {
+			switch {
+			case i > j:
+				homeWin += p
+			case i == j:
+				draw += p
+			default:
+				awayWin += p
+			}
+			expectedHome += float64(i) * p
+			expectedAway += float64(j) * p
+			scorelines = append(scorelines, ScorelinePrediction{HomeGoals: i, AwayGoals: j, Probability: p})
+		}
+	}
+	total := homeWin + draw + awayWin
+	slices.SortStableFunc(scorelines, func(a, b ScorelinePrediction) int { return cmp.Compare(b.Probability, a.Probability) })
+	scorelines = scorelines[:min(predictedScorelines, len(scorelines))]
+	for i := range scorelines {
+		scorelines[i].Probability = roundPercentage(scorelines[i].Probability / total)
+	}
+
+	return MatchPrediction{
+		MatchID:           m.ID,
+		Week:              m.Week,
+		HomeTeamID:        m.HomeTeamID,
+		AwayTeamID:        m.AwayTeamID,
+		NameHome:          m.NameHome,
+		NameAway:          m.NameAway,
+		Postponed:         m.Postponed,
+		HomeWin:           roundPercentage(homeWin / total),
+		Draw:              roundPercentage(draw / total),
+		AwayWin:           roundPercentage(awayWin / total),
+		ExpectedHomeGoals: math.Round(expectedHome/total*1000) / 1000,
+		ExpectedAwayGoals: math.Round(expectedAway/total*1000) / 1000,
+		Scorelines:        scorelines,
+	}
+}
+
+// roundPercentage converts a probability to a percentage rounded to three decimal places
+func roundPercentage(p float64) float64 {
+	return math.Round(p*100*1000) / 1000
+}
+
+// PredictionHandler handles the request for the prediction of a single unplayed match
+func PredictionHandler(matchService MatchService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := pathID(c, "id")
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		prediction, err := matchService.Prediction(id)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"engine":     matchService.Settings().Engine.Name(),
+			"prediction": prediction,
+		})
+	}
+}
+
+// PredictionsHandler handles the request for the predictions of the unplayed matches of a week, ?week= defaults to the next week
+func PredictionsHandler(matchService MatchService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		week := 0
+		if value := c.Query("week"); value != "" {
+			var err error
+			week, err = strconv.Atoi(value)
+			if err != nil || week < 1 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("week must be a positive number, got %q", value)})
+				return
+			}
+		}
+		week, predictions, err := matchService.Predictions(week)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"engine":      matchService.Settings().Engine.Name(),
+			"week":        week,
+			"predictions": predictions,
+		})
+	}
+}
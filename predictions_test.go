@@ -0,0 +1,65 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// testEngines returns every match engine, the fitted one with parameters for the sample teams
+func testEngines() []MatchEngine {
+	fitted := &fittedEngine{}
+	fitted.use(FittedModel{HomeAdvantage: 1.25, BaseGoals: 1.2, Teams: map[int]FittedParameters{
+		1: {Attack: 1.1, Defence: 1}, 2: {Attack: 1.5, Defence: 1.4}, 3: {Attack: 0.7, Defence: 0.8}, 4: {Attack: 1.2, Defence: 1.1},
+	}})
+	return []MatchEngine{legacyEngine{}, poissonEngine{}, dixonColesEngine{rho: defaultDixonColesRho}, fitted}
+}
+
+func TestPredictionsAgreeWithTheEngine(t *testing.T) {
+	teams := sampleTeams()
+	pairs := [][2]Team{{teams[0], teams[1]}, {teams[1], teams[2]}, {teams[2], teams[3]}, {teams[3], teams[0]}}
+	for _, engine := range testEngines() {
+		for _, pair := range pairs {
+			home, away := pair[0], pair[1]
+			name := engine.Name() + " " + home.Name + " v " + away.Name
+
+			// The matrix stops at maxMatrixGoals per side, so only a sliver of probability may be missing
+			sum := 0.0
+			for _, row := range engine.ScoreProbabilities(home, away) {
+				for _, p := range row {
+					if p < 0 {
+						t.Fatalf("%s: negative scoreline probability %v", name, p)
+					}
+					sum += p
+				}
+			}
+			if sum > 1+1e-9 || sum < 0.999 {
+				t.Errorf("%s: scoreline probabilities add up to %v", name, sum)
+			}
+
+			prediction := predictMatch(engine, Match{HomeTeamID: home.ID, AwayTeamID: away.ID}, home, away)
+			if total := prediction.HomeWin + prediction.Draw + prediction.AwayWin; math.Abs(total-100) > 0.002 {
+				t.Errorf("%s: home %v, draw %v and away %v add up to %v", name, prediction.HomeWin, prediction.Draw, prediction.AwayWin, total)
+			}
+			top := 0.0
+			for i, s := range prediction.Scorelines {
+				if i > 0 && s.Probability > prediction.Scorelines[i-1].Probability {
+					t.Errorf("%s: scorelines not in decreasing order: %+v", name, prediction.Scorelines)
+				}
+				top += s.Probability
+			}
+			if len(prediction.Scorelines) != predictedScorelines || top > 100 {
+				t.Errorf("%s: %d scorelines with %v%% between them", name, len(prediction.Scorelines), top)
+			}
+
+			// The Poisson engines' matrices have the means they were built from. The legacy engine only anchors its buckets
+			// on the whole part of its expected goals and adds 0.25 + 2 × 0.15 + 3 × 0.1 + (0+1+2+3+4) × 0.02 = 1.05 on average.
+			lambda, mu := engine.ExpectedGoals(home, away)
+			if engine.Name() == "legacy" {
+				lambda, mu = math.Floor(lambda)+1.05, math.Floor(mu)+1.05
+			}
+			if math.Abs(prediction.ExpectedHomeGoals-lambda) > 0.01 || math.Abs(prediction.ExpectedAwayGoals-mu) > 0.01 {
+				t.Errorf("%s: expected goals %v-%v, engine expects %.3f-%.3f", name, prediction.ExpectedHomeGoals, prediction.ExpectedAwayGoals, lambda, mu)
+			}
+		}
+	}
+}
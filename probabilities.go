@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Zone is a block of table positions counted from the top or from the bottom of the table
+type Zone struct {
+	Name   string `json:"name"`
+	Top    int    `json:"top,omitempty"`
+	Bottom int    `json:"bottom,omitempty"`
+}
+
+// defaultZones are reported when a request does not name any zone
+var defaultZones = []Zone{
+	{Name: "title", Top: 1},
+	{Name: "relegation", Bottom: 1},
+}
+
+// contains reports whether a zero based position of a table with size teams is inside the zone
+func (z Zone) contains(position, size int) bool {
+	if z.Top > 0 && position < z.Top {
+		return true
+	}
+	return z.Bottom > 0 && position >= size-z.Bottom
+}
+
+// parseZone reads a zone written as name:top:N or name:bottom:N
+func parseZone(value string) (Zone, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 || parts[0] == "" {
+		return Zone{}, fmt.Errorf("zone must look like name:top:N or name:bottom:N, got %q", value)
+	}
+	n, err := strconv.Atoi(parts[2])
+	if err != nil || n < 1 {
+		return Zone{}, fmt.Errorf("zone %q must cover at least one position", value)
+	}
+	switch parts[1] {
+	case "top":
+		return Zone{Name: parts[0], Top: n}, nil
+	case "bottom":
+		return Zone{Name: parts[0], Bottom: n}, nil
+	}
+	return Zone{}, fmt.Errorf("zone %q must count from the top or the bottom", value)
+}
+
+// PointsPercentiles summarizes the distribution of a team's final points
+type PointsPercentiles struct {
+	P5  int `json:"p5"`
+	P25 int `json:"p25"`
+	P50 int `json:"p50"`
+	P75 int `json:"p75"`
+	P95 int `json:"p95"`
+}
+
+// TeamProjection is the simulated outcome of the season for one team
+type TeamProjection struct {
+	TeamID            int                `json:"team_id"`
+	Name              string             `json:"name"`
+	CurrentPosition   int                `json:"current_position"`
+	CurrentPoints     int                `json:"current_points"`
+	Positions         []float64          `json:"positions"` // Positions[i] is the probability in % of finishing (i+1)th
+	ExpectedPoints    float64            `json:"expected_points"`
+	PointsPercentiles PointsPercentiles  `json:"points_percentiles"`
+	Zones             map[string]float64 `json:"zones"`
+}
+
+// SeasonProjection is the response of GET /probabilities
+type SeasonProjection struct {
+	Simulations      int              `json:"simulations"`
+	RemainingMatches int              `json:"remaining_matches"`
+	Engine           string           `json:"engine"`
+	Zones            []Zone           `json:"zones"`
+	Teams            []TeamProjection `json:"teams"`
+}
+
+// positionCounter records every team's finishing positions and final points, indexed like the starting table
+type positionCounter struct {
+	index     map[int]int
+	positions [][]int
+	points    [][]int // points[team][p] is how often the team finished with p+offset points
+	offset    int     // lowest starting points, below zero after deductions; points never go down from there
+	total     int
+}
+
+// newPositionCounterFor creates the observer factory for a starting table
+func newPositionCounterFor(table []Team) func() simulationObserver {
+	index := make(map[int]int, len(table))
+	offset := 0
+	for i, t := range table {
+		index[t.ID] = i
+		offset = min(offset, t.Points)
+	}
+	return func() simulationObserver {
+		c := &positionCounter{
+			index:     index,
+			positions: make([][]int, len(table)),
+			points:    make([][]int, len(table)),
+			offset:    offset,
+		}
+		for i := range table {
+			c.positions[i] = make([]int, len(table))
+		}
+		return c
+	}
+}
+
+// observe records the final table of one simulated season
+func (c *positionCounter) observe(table []Team) {
+	for position, t := range table {
+		i := c.index[t.ID]
+		c.positions[i][position]++
+		c.points[i] = addToHistogram(c.points[i], t.Points-c.offset, 1)
+	}
+	c.total++
+}
+
+// merge adds the counts of another shard
+func (c *positionCounter) merge(other simulationObserver) {
+	o := other.(*positionCounter)
+	for i := range o.positions {
+		for position, count := range o.positions[i] {
+			c.positions[i][position] += count
+		}
+		for points, count := range o.points[i] {
+			c.points[i] = addToHistogram(c.points[i], points, count)
+		}
+	}
+	c.total += o.total
+}
+
+// addToHistogram increments bucket value, growing the histogram when needed
+func addToHistogram(histogram []int, value, count int) []int {
+	if value < 0 {
+		value = 0
+	}
+	for len(histogram) <= value {
+		histogram = append(histogram, 0)
+	}
+	histogram[value] += count
+	return histogram
+}
+
+// histogramPercentile returns the smallest value with at least p% of the samples at or below it
+func histogramPercentile(histogram []int, total int, p float64) int {
+	target := int(math.Ceil(p / 100 * float64(total)))
+	seen := 0
+	for value, count := range histogram {
+		seen += count
+		if seen >= target && seen > 0 {
+			return value
+		}
+	}
+	return len(histogram) - 1
+}
+
+// SimulateSeasonProjection simulates every unplayed match and reports the full finishing position distribution
+func SimulateSeasonProjection(teamService TeamService, matchService MatchService, settings LeagueSettings, rng *rand.Rand, zones []Zone) (*SeasonProjection, error) {
+	teams, err := teamService.GetTeams()
+	if err != nil {
+		return nil, err
+	}
+	matches, err := matchService.GetMatches()
+	if err != nil {
+		return nil, err
+	}
+
+	simulation := newSeasonSimulation(teams, matches, settings)
+	projection := &SeasonProjection{
+		Simulations:      settings.Simulations,
+		RemainingMatches: len(simulation.fixtures),
+		Engine:           settings.Engine.Name(),
+		Zones:            zones,
+		Teams:            []TeamProjection{},
+	}
+	if len(simulation.table) == 0 {
+		return projection, nil
+	}
+	counter := simulation.run(rng, newPositionCounterFor(simulation.table)).(*positionCounter)
+
+	for i, t := range simulation.table {
+		team := TeamProjection{
+			TeamID:          t.ID,
+			Name:            t.Name,
+			CurrentPosition: i + 1,
+			CurrentPoints:   t.Points,
+			Positions:       make([]float64, len(simulation.table)),
+			Zones:           make(map[string]float64, len(zones)),
+		}
+
+		zoneCounts := make([]int, len(zones))
+		for position, count := range counter.positions[i] {
+			team.Positions[position] = percentage(count, counter.total)
+			for z, zone := range zones {
+				if zone.contains(position, len(simulation.table)) {
+					zoneCounts[z] += count
+				}
+			}
+		}
+		for z, zone := range zones {
+			team.Zones[zone.Name] = percentage(zoneCounts[z], counter.total)
+		}
+
+		sum := 0
+		for points, count := range counter.points[i] {
+			sum += (points + counter.offset) * count
+		}
+		team.ExpectedPoints = math.Round(float64(sum)/float64(counter.total)*1000) / 1000
+		histogram := counter.points[i]
+		team.PointsPercentiles = PointsPercentiles{
+			P5:  histogramPercentile(histogram, counter.total, 5) + counter.offset,
+			P25: histogramPercentile(histogram, counter.total, 25) + counter.offset,
+			P50: histogramPercentile(histogram, counter.total, 50) + counter.offset,
+			P75: histogramPercentile(histogram, counter.total, 75) + counter.offset,
+			P95: histogramPercentile(histogram, counter.total, 95) + counter.offset,
+		}
+		projection.Teams = append(projection.Teams, team)
+	}
+	return projection, nil
+}
+
+// ProbabilitiesHandler handles the request for the finishing position matrix of the current season
+func ProbabilitiesHandler(teamService TeamService, matchService MatchService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		seed, err := requestSeed(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		simulations, err := requestSimulations(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Zones are given as repeated ?zone=name:top:N or ?zone=name:bottom:N parameters
+		zones := defaultZones
+		if values := c.QueryArray("zone"); len(values) > 0 {
+			zones = nil
+			for _, value := range values {
+				zone, err := parseZone(value)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				zones = append(zones, zone)
+			}
+		}
+
+		settings := matchService.Settings()
+		if simulations > 0 {
+			settings.Simulations = simulations
+		}
+		projection, err := SimulateSeasonProjection(teamService, matchService, settings, newRNG(seed), zones)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"projection": projection,
+			"seed":       seed,
+		})
+	}
+}
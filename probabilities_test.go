@@ -0,0 +1,60 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestProjectionDistributionsAddUp(t *testing.T) {
+	for _, engine := range testEngines() {
+		settings := benchmarkSettings()
+		settings.Engine = engine
+		settings.Simulations = 2000
+		leagueService := &MyLeagueService{store: newMemoryStore(sampleTeams()), settings: settings}
+		services, err := leagueService.SeasonServices(1, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rng := newRNG(1)
+		for range 3 {
+			if _, _, err := services.matches.PlayWeek(rng); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		projection, err := SimulateSeasonProjection(services.teams, services.matches, settings, newRNG(1), defaultZones)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if projection.Engine != engine.Name() || projection.RemainingMatches != 6 || len(projection.Teams) != 4 {
+			t.Fatalf("%s: projection %+v", engine.Name(), projection)
+		}
+
+		// Every team finishes somewhere and every position is taken by someone, up to rounding
+		columns := make([]float64, len(projection.Teams))
+		for _, team := range projection.Teams {
+			row := 0.0
+			for position, p := range team.Positions {
+				row += p
+				columns[position] += p
+			}
+			if math.Abs(row-100) > 0.01 {
+				t.Errorf("%s: %s finishes in some position with %v%%", engine.Name(), team.Name, row)
+			}
+			if team.Zones["title"] != team.Positions[0] || team.Zones["relegation"] != team.Positions[len(team.Positions)-1] {
+				t.Errorf("%s: %s zones %v do not match positions %v", engine.Name(), team.Name, team.Zones, team.Positions)
+			}
+			// Three matches are left for every team
+			p := team.PointsPercentiles
+			if team.ExpectedPoints < float64(team.CurrentPoints) || team.ExpectedPoints > float64(team.CurrentPoints+9) ||
+				p.P5 > p.P25 || p.P25 > p.P50 || p.P50 > p.P75 || p.P75 > p.P95 {
+				t.Errorf("%s: %s on %d points expects %v with percentiles %+v", engine.Name(), team.Name, team.CurrentPoints, team.ExpectedPoints, p)
+			}
+		}
+		for position, sum := range columns {
+			if math.Abs(sum-100) > 0.01 {
+				t.Errorf("%s: position %d is taken with %v%%", engine.Name(), position+1, sum)
+			}
+		}
+	}
+}
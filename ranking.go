@@ -0,0 +1,177 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+)
+
+// Tiebreaker is one rule ranking teams that are level on points
+type Tiebreaker string
+
+const (
+	tiebreakGoalDifference Tiebreaker = "goal_difference"
+	tiebreakGoalsFor       Tiebreaker = "goals_for"
+	tiebreakWins           Tiebreaker = "wins"
+	tiebreakH2HPoints      Tiebreaker = "head_to_head_points"
+	tiebreakH2HGoalDiff    Tiebreaker = "head_to_head_goal_difference"
+	tiebreakH2HGoalsFor    Tiebreaker = "head_to_head_goals_for"
+	tiebreakH2HAwayGoals   Tiebreaker = "head_to_head_away_goals"
+	tiebreakFairPlay       Tiebreaker = "fair_play"
+	tiebreakLots           Tiebreaker = "lots"
+)
+
+// DecidedBy values that are not tiebreakers
+const (
+	decidedByPoints  = "points"
+	decidedByNothing = "tied"
+)
+
+// tiebreakers lists every known rule
+var tiebreakers = []Tiebreaker{
+	tiebreakGoalDifference, tiebreakGoalsFor, tiebreakWins,
+	tiebreakH2HPoints, tiebreakH2HGoalDiff, tiebreakH2HGoalsFor, tiebreakH2HAwayGoals,
+	tiebreakFairPlay, tiebreakLots,
+}
+
+// parseTiebreakers checks a configured list of rule names
+func parseTiebreakers(names []string) ([]Tiebreaker, error) {
+	rules := make([]Tiebreaker, 0, len(names))
+	for _, name := range names {
+		rule := Tiebreaker(strings.TrimSpace(name))
+		if !slices.Contains(tiebreakers, rule) {
+			return nil, fmt.Errorf("unknown tiebreaker %q", name)
+		}
+		if slices.Contains(rules, rule) {
+			return nil, fmt.Errorf("tiebreaker %q is listed twice", name)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// headToHead reports whether the rule looks at the matches between the tied teams
+func (t Tiebreaker) headToHead() bool {
+	return strings.HasPrefix(string(t), "head_to_head_")
+}
+
+// matchResult is a played or simulated score between two teams addressed by id
+type matchResult struct {
+	homeID, awayID       int
+	homeGoals, awayGoals int
+}
+
+// playedResults collects the scores of every played match
+func playedResults(matches []Match) []matchResult {
+	results := make([]matchResult, 0, len(matches))
+	for _, m := range matches {
+		if m.Played && m.HomeGoals != nil && m.AwayGoals != nil {
+			results = append(results, matchResult{m.HomeTeamID, m.AwayTeamID, *m.HomeGoals, *m.AwayGoals})
+		}
+	}
+	return results
+}
+
+// needsResults reports whether ranking needs the individual match results and not only the table
+func (s LeagueSettings) needsResults() bool {
+	return slices.ContainsFunc(s.Tiebreakers, Tiebreaker.headToHead)
+}
+
+// rankTable sorts the table by points and then by the configured tiebreakers, one rule at a time on each group of
+// teams still level, and sets DecidedBy on every team. results are only read by head-to-head rules.
+// Drawing of lots uses rng; without one, as in the stored table, teams still level keep their order by id.
+func (s LeagueSettings) rankTable(table []Team, results []matchResult, rng *rand.Rand) {
+	slices.SortStableFunc(table, func(a, b Team) int { return b.Points - a.Points })
+	for i := range table {
+		table[i].DecidedBy = decidedByNothing
+	}
+	s.splitGroups(table, results, rng, -1, func(t Team) int { return t.Points })
+	// The last team is described by the rule that separated it from the team above
+	if n := len(table); n > 1 {
+		table[n-1].DecidedBy = table[n-2].DecidedBy
+	}
+}
+
+// splitGroups labels the boundaries between groups of equal key in an already sorted slice
+// and ranks every group of more than one team with the next rule
+func (s LeagueSettings) splitGroups(group []Team, results []matchResult, rng *rand.Rand, depth int, key func(Team) int) {
+	decidedBy := decidedByPoints
+	if depth >= 0 {
+		decidedBy = string(s.Tiebreakers[depth])
+	}
+	start := 0
+	for i := 1; i <= len(group); i++ {
+		if i < len(group) && key(group[i]) == key(group[start]) {
+			continue
+		}
+		if i-start > 1 {
+			s.rankGroup(group[start:i], results, rng, depth+1)
+		}
+		// Labelled after ranking the group, which can reorder its teams
+		if i < len(group) {
+			group[i-1].DecidedBy = decidedBy
+		}
+		start = i
+	}
+}
+
+// rankGroup orders teams level on every rule before depth with the rule at depth
+func (s LeagueSettings) rankGroup(group []Team, results []matchResult, rng *rand.Rand, depth int) {
+	if depth >= len(s.Tiebreakers) {
+		return
+	}
+	key := s.tiebreakKey(s.Tiebreakers[depth], group, results, rng)
+	slices.SortStableFunc(group, func(a, b Team) int { return cmp.Compare(key(b), key(a)) })
+	s.splitGroups(group, results, rng, depth, key)
+}
+
+// tiebreakKey returns the value a rule ranks a group of tied teams by, higher ranks first
+func (s LeagueSettings) tiebreakKey(rule Tiebreaker, group []Team, results []matchResult, rng *rand.Rand) func(Team) int {
+	switch rule {
+	case tiebreakGoalDifference:
+		return func(t Team) int { return t.GoalDiff }
+	case tiebreakGoalsFor:
+		return func(t Team) int { return t.GoalsFor }
+	case tiebreakWins:
+		return func(t Team) int { return t.Wins }
+	case tiebreakFairPlay:
+		// Fewer disciplinary points rank higher
+		return func(t Team) int { return -t.FairPlayPoints }
+	case tiebreakLots:
+		draw := make(map[int]int, len(group))
+		for _, t := range group {
+			draw[t.ID] = -t.ID
+			if rng != nil {
+				draw[t.ID] = rng.Int()
+			}
+		}
+		return func(t Team) int { return draw[t.ID] }
+	}
+
+	// Head-to-head rules build a mini table from the matches between the tied teams only
+	mini := make(map[int]*Team, len(group))
+	for _, t := range group {
+		mini[t.ID] = &Team{ID: t.ID}
+	}
+	awayGoals := make(map[int]int, len(group))
+	for _, r := range results {
+		home, away := mini[r.homeID], mini[r.awayID]
+		if home == nil || away == nil {
+			continue
+		}
+		applyResult(home, away, r.homeGoals, r.awayGoals, s.Points)
+		awayGoals[r.awayID] += r.awayGoals
+	}
+	switch rule {
+	case tiebreakH2HPoints:
+		return func(t Team) int { return mini[t.ID].Points }
+	case tiebreakH2HGoalDiff:
+		return func(t Team) int { return mini[t.ID].GoalDiff }
+	case tiebreakH2HGoalsFor:
+		return func(t Team) int { return mini[t.ID].GoalsFor }
+	default:
+		return func(t Team) int { return awayGoals[t.ID] }
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// playedMatch is a played match between two teams addressed by id
+func playedMatch(home, away, homeGoals, awayGoals int) Match {
+	return Match{HomeTeamID: home, AwayTeamID: away, HomeGoals: &homeGoals, AwayGoals: &awayGoals, Played: true, Week: 1}
+}
+
+// rankingTeams returns teams 1..n named A, B, C, ...
+func rankingTeams(n int) []Team {
+	teams := make([]Team, n)
+	for i := range teams {
+		teams[i] = Team{ID: i + 1, Name: string(rune('A' + i)), Strength: 50}
+	}
+	return teams
+}
+
+// checkRanking compares the order of a table and the rule that decided every position
+func checkRanking(t *testing.T, table []Team, names []string, decidedBy []string) {
+	t.Helper()
+	var gotNames, gotDecidedBy []string
+	for _, team := range table {
+		gotNames = append(gotNames, team.Name)
+		gotDecidedBy = append(gotDecidedBy, team.DecidedBy)
+	}
+	if !slices.Equal(gotNames, names) || !slices.Equal(gotDecidedBy, decidedBy) {
+		t.Errorf("ranked %v decided by %v, want %v decided by %v", gotNames, gotDecidedBy, names, decidedBy)
+	}
+}
+
+func TestHeadToHeadOnlyCountsMatchesBetweenTiedTeams(t *testing.T) {
+	settings := benchmarkSettings()
+	settings.Tiebreakers = []Tiebreaker{tiebreakH2HPoints, tiebreakH2HGoalDiff}
+
+	// A, B and C beat each other in a circle and all beat D, so they are level on 6 points and 3 head-to-head points.
+	// Between themselves A is +1, C 0 and B -1. B's 9-0 against D would put it first if the mini table counted it.
+	matches := []Match{
+		playedMatch(1, 2, 2, 0), playedMatch(2, 3, 1, 0), playedMatch(3, 1, 1, 0),
+		playedMatch(1, 4, 1, 0), playedMatch(2, 4, 9, 0), playedMatch(3, 4, 1, 0),
+	}
+	table := computeStandings(rankingTeams(4), matches, settings)
+	checkRanking(t, table, []string{"A", "C", "B", "D"},
+		[]string{"head_to_head_goal_difference", "head_to_head_goal_difference", "points", "points"})
+}
+
+func TestHeadToHeadRebuildsTheMiniTableOfTheTeamsStillLevel(t *testing.T) {
+	settings := benchmarkSettings()
+	settings.Tiebreakers = []Tiebreaker{tiebreakH2HPoints, tiebreakH2HGoalDiff, tiebreakGoalDifference}
+
+	// A, B and C finish on 7 points. A won both games against the other two and goes first on head-to-head points.
+	// B and C drew, so their own mini table is level and goal difference puts C (+4) ahead of B (+1).
+	// In the mini table of all three B (-1) would have been ahead of C (-3).
+	matches := []Match{
+		playedMatch(1, 2, 1, 0), playedMatch(1, 3, 3, 0), playedMatch(2, 3, 1, 1),
+		playedMatch(1, 4, 0, 0), playedMatch(5, 1, 1, 0),
+		playedMatch(2, 4, 1, 0), playedMatch(2, 5, 1, 0),
+		playedMatch(3, 4, 6, 0), playedMatch(3, 5, 1, 0),
+	}
+	table := computeStandings(rankingTeams(5), matches, settings)
+	checkRanking(t, table, []string{"A", "C", "B", "E", "D"},
+		[]string{"head_to_head_points", "goal_difference", "points", "points", "points"})
+}
+
+func TestFairPlayRanksFewerPointsHigher(t *testing.T) {
+	settings := benchmarkSettings()
+	settings.Tiebreakers = []Tiebreaker{tiebreakGoalDifference, tiebreakFairPlay}
+
+	teams := rankingTeams(3)
+	teams[0].FairPlayPoints, teams[1].FairPlayPoints, teams[2].FairPlayPoints = 9, 2, 2
+	table := computeStandings(teams, nil, settings)
+	checkRanking(t, table, []string{"B", "C", "A"}, []string{"tied", "fair_play", "fair_play"})
+}
+
+func TestSetFairPlayPointsIsStoredAndRanked(t *testing.T) {
+	settings := benchmarkSettings()
+	settings.Tiebreakers = []Tiebreaker{tiebreakFairPlay}
+	leagueService := &MyLeagueService{store: newMemoryStore(sampleTeams()), settings: settings}
+	services, err := leagueService.SeasonServices(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for id, points := range map[int]int{1: 3, 2: 1, 3: 2} {
+		if _, err := services.teams.SetFairPlayPoints(id, points); err != nil {
+			t.Fatalf("team %d: %v", id, err)
+		}
+	}
+	if _, err := services.teams.SetFairPlayPoints(99, 1); err == nil {
+		t.Error("set fair play points of a team outside the season")
+	}
+
+	// Rebuilding the table recomputes every counter but must keep the fair play points
+	table, err := services.teams.RebuildStandings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids, points []int
+	for _, team := range table {
+		ids, points = append(ids, team.ID), append(points, team.FairPlayPoints)
+	}
+	if !slices.Equal(ids, []int{4, 2, 3, 1}) || !slices.Equal(points, []int{0, 1, 2, 3}) {
+		t.Errorf("ranked teams %v with fair play points %v, want [4 2 3 1] with [0 1 2 3]", ids, points)
+	}
+}
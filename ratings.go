@@ -0,0 +1,186 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// A team's static strength maps to its starting rating: baseStrength starts at baseRating
+// and every doubling of strength is worth the same number of rating points
+const (
+	baseRating   = 1500.0
+	baseStrength = 50.0
+)
+
+// RatingSettings configures the Elo ratings replayed from the played matches
+type RatingSettings struct {
+	KFactor       float64 // points exchanged by a one goal win against an equal team, 0 keeps the static strengths
+	HomeAdvantage float64 // rating points added to the home team when predicting the result
+}
+
+// enabled reports whether the engines play with the ratings instead of the static strengths
+func (r RatingSettings) enabled() bool {
+	return r.KFactor > 0
+}
+
+// RatingPoint is the rating of a team at the end of a week, week 0 is the starting rating
+type RatingPoint struct {
+	TeamID int     `json:"team_id"`
+	Week   int     `json:"week"`
+	Rating float64 `json:"rating"`
+}
+
+// TeamRatings is the current rating of a team with its history
+type TeamRatings struct {
+	TeamID  int           `json:"team_id"`
+	Name    string        `json:"name"`
+	Rating  float64       `json:"rating"`
+	History []RatingPoint `json:"history"`
+}
+
+// ratingFromStrength returns the starting rating of a team
+func ratingFromStrength(strength int) float64 {
+	return baseRating + 400*math.Log10(float64(max(strength, 1))/baseStrength)
+}
+
+// power is the strength the match engines play with: the rating converted back to the strength scale,
+// or the static strength when the team has no rating. The ratio of two powers is the Elo odds of the two teams.
+func (t Team) power() float64 {
+	if t.Rating == 0 {
+		return float64(t.Strength)
+	}
+	return baseStrength * math.Pow(10, (t.Rating-baseRating)/400)
+}
+
+// expectedScore is the Elo expectation of the first team, a win counting 1 and a draw 0.5
+func expectedScore(diff float64) float64 {
+	return 1 / (1 + math.Pow(10, -diff/400))
+}
+
+// marginMultiplier scales the rating change by the margin of victory, as in the World Football Elo Ratings
+func marginMultiplier(goalDiff int) float64 {
+	switch goalDiff = max(goalDiff, -goalDiff); {
+	case goalDiff <= 1:
+		return 1
+	case goalDiff == 2:
+		return 1.5
+	default:
+		return (11 + float64(goalDiff)) / 8
+	}
+}
+
+// replayRatings rates every team from its static strength and updates both teams after each played match in week order.
+// Matches of the same week are rated from the ratings before the week. A shootout counts as a draw.
+// It returns the current ratings and the rating of every team at the end of every week it played, rounded to 0.1,
+// or an error when a match has negative goals or a rating stops being a finite number.
+func replayRatings(teams []Team, matches []Match, settings RatingSettings) (map[int]float64, []RatingPoint, error) {
+	ratings := make(map[int]float64, len(teams))
+	history := make([]RatingPoint, 0, len(teams))
+	for _, t := range teams {
+		ratings[t.ID] = ratingFromStrength(t.Strength)
+		history = append(history, RatingPoint{TeamID: t.ID, Week: 0, Rating: roundRating(ratings[t.ID])})
+	}
+
+	played := slices.DeleteFunc(slices.Clone(matches), func(m Match) bool {
+		if !m.Played || m.HomeGoals == nil || m.AwayGoals == nil {
+			return true
+		}
+		_, homeOK := ratings[m.HomeTeamID]
+		_, awayOK := ratings[m.AwayTeamID]
+		return !homeOK || !awayOK
+	})
+	slices.SortStableFunc(played, func(a, b Match) int {
+		return cmp.Or(cmp.Compare(a.Week, b.Week), cmp.Compare(a.ID, b.ID))
+	})
+
+	for start := 0; start < len(played); {
+		week := played[start].Week
+		end := start
+		change := make(map[int]float64)
+		for ; end < len(played) && played[end].Week == week; end++ {
+			m := played[end]
+			homeGoals, awayGoals := *m.HomeGoals, *m.AwayGoals
+			if homeGoals < 0 || awayGoals < 0 {
+				return nil, nil, fmt.Errorf("%w: match %d has negative goals", errInvalidInput, m.ID)
+			}
+			score := 0.5
+			if homeGoals > awayGoals {
+				score = 1
+			} else if homeGoals < awayGoals {
+				score = 0
+			}
+			expected := expectedScore(ratings[m.HomeTeamID] + settings.HomeAdvantage - ratings[m.AwayTeamID])
+			delta := settings.KFactor * marginMultiplier(homeGoals-awayGoals) * (score - expected)
+			change[m.HomeTeamID] += delta
+			change[m.AwayTeamID] -= delta
+		}
+		for _, t := range teams {
+			if delta, ok := change[t.ID]; ok {
+				ratings[t.ID] += delta
+				if math.IsNaN(ratings[t.ID]) || math.IsInf(ratings[t.ID], 0) {
+					return nil, nil, fmt.Errorf("rating of team %d is not a number after week %d", t.ID, week)
+				}
+				history = append(history, RatingPoint{TeamID: t.ID, Week: week, Rating: roundRating(ratings[t.ID])})
+			}
+		}
+		start = end
+	}
+
+	for id, rating := range ratings {
+		ratings[id] = roundRating(rating)
+	}
+	return ratings, history, nil
+}
+
+// roundRating rounds a rating to one decimal place
+func roundRating(rating float64) float64 {
+	return math.Round(rating*10) / 10
+}
+
+// Ratings returns the stored rating history of every team, highest current rating first
+func (s *MyTeamService) Ratings() ([]TeamRatings, error) {
+	if !s.settings.Ratings.enabled() {
+		return nil, fmt.Errorf("%w: ratings are disabled, set ratings.k_factor to enable them", errInvalidInput)
+	}
+	teams, err := s.repo.Teams()
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.repo.Ratings()
+	if err != nil {
+		return nil, err
+	}
+
+	byTeam := make(map[int][]RatingPoint, len(teams))
+	for _, p := range history {
+		byTeam[p.TeamID] = append(byTeam[p.TeamID], p)
+	}
+	ratings := make([]TeamRatings, len(teams))
+	for i, t := range teams {
+		points := byTeam[t.ID]
+		// Nothing is stored before the first result is written, the team still has its starting rating
+		if len(points) == 0 {
+			points = []RatingPoint{{TeamID: t.ID, Week: 0, Rating: roundRating(ratingFromStrength(t.Strength))}}
+		}
+		ratings[i] = TeamRatings{TeamID: t.ID, Name: t.Name, Rating: points[len(points)-1].Rating, History: points}
+	}
+	slices.SortStableFunc(ratings, func(a, b TeamRatings) int { return cmp.Compare(b.Rating, a.Rating) })
+	return ratings, nil
+}
+
+// RatingsHandler handles the request for the current ratings and their history week by week
+func RatingsHandler(teamService TeamService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ratings, err := teamService.Ratings()
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ratings": ratings})
+	}
+}
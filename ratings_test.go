@@ -0,0 +1,121 @@
+package main
+
+import (
+	"cmp"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestReplayRatingsByHand(t *testing.T) {
+	teams := []Team{{ID: 1, Strength: 50}, {ID: 2, Strength: 50}}
+	first, second := playedMatch(1, 2, 2, 0), playedMatch(2, 1, 1, 1)
+	second.Week = 2
+	ratings, history, err := replayRatings(teams, []Match{first, second}, RatingSettings{KFactor: 20, HomeAdvantage: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Week 1: the home side expected 1 / (1 + 10^(-60/400)) = 0.5855 and won by two, 20 × 1.5 × (1 - 0.5855) = 12.4.
+	// Week 2: team 2 hosts on 1487.6 + 60 against 1512.4, expects 0.5504 and draws, 20 × (0.5 - 0.5504) = -1.0.
+	want := []RatingPoint{
+		{1, 0, 1500}, {2, 0, 1500},
+		{1, 1, 1512.4}, {2, 1, 1487.6},
+		{1, 2, 1513.4}, {2, 2, 1486.6},
+	}
+	if !reflect.DeepEqual(history, want) {
+		t.Errorf("history %v, want %v", history, want)
+	}
+	if ratings[1] != 1513.4 || ratings[2] != 1486.6 {
+		t.Errorf("ratings %v, want 1513.4 and 1486.6", ratings)
+	}
+}
+
+func TestReplayRatingsRatesAWeekFromTheRatingsBeforeIt(t *testing.T) {
+	teams := rankingTeams(3)
+	settings := RatingSettings{KFactor: 20, HomeAdvantage: 60}
+	// Team 1 plays twice in week 1, the order of the two matches must not matter
+	matches := []Match{playedMatch(1, 2, 3, 0), playedMatch(3, 1, 0, 1)}
+	matches[0].ID, matches[1].ID = 1, 2
+	swapped := []Match{matches[1], matches[0]}
+	swapped[0].ID, swapped[1].ID = 1, 2
+
+	forward, _, _ := replayRatings(teams, matches, settings)
+	backward, _, _ := replayRatings(teams, swapped, settings)
+	if !reflect.DeepEqual(forward, backward) {
+		t.Errorf("the order of the matches of a week changed the ratings: %v and %v", forward, backward)
+	}
+}
+
+func TestChangedResultReplaysEveryLaterWeek(t *testing.T) {
+	settings := benchmarkSettings()
+	leagueService := &MyLeagueService{store: newMemoryStore(sampleTeams()), settings: settings}
+	services, err := leagueService.SeasonServices(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := newRNG(1)
+	for {
+		if _, _, err := services.matches.PlayWeek(rng); err != nil {
+			break
+		}
+	}
+	history := func() []RatingPoint {
+		stored, err := services.matches.repo.Ratings()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stored
+	}
+	before := history()
+
+	// Turn a week 1 result into a big away win
+	matches, _ := services.matches.GetMatches()
+	edited := matches[slices.IndexFunc(matches, func(m Match) bool { return m.Week == 1 })]
+	if _, _, err := services.matches.ChangeMatchResult(edited.ID, 0, 5); err != nil {
+		t.Fatal(err)
+	}
+	after := history()
+
+	// The stored history is exactly a replay of the edited season from scratch
+	teams, _ := services.teams.GetTeams()
+	matches, _ = services.matches.GetMatches()
+	_, replayed, err := replayRatings(teams, matches, settings.Ratings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.SortStableFunc(replayed, func(a, b RatingPoint) int {
+		return cmp.Or(cmp.Compare(a.TeamID, b.TeamID), cmp.Compare(a.Week, b.Week))
+	})
+	if !reflect.DeepEqual(after, replayed) {
+		t.Errorf("stored history after the edit is not the replay of the season:\n%v\n%v", after, replayed)
+	}
+
+	// The starting ratings stay, the teams of the edited match move in week 1 and every week after it
+	for _, id := range []int{edited.HomeTeamID, edited.AwayTeamID} {
+		for _, p := range after {
+			if p.TeamID != id {
+				continue
+			}
+			i := slices.IndexFunc(before, func(q RatingPoint) bool { return q.TeamID == id && q.Week == p.Week })
+			if changed := before[i].Rating != p.Rating; changed != (p.Week > 0) {
+				t.Errorf("team %d week %d: rating %v before the edit and %v after", id, p.Week, before[i].Rating, p.Rating)
+			}
+		}
+	}
+}
+
+func TestBrokenReplayKeepsRatingsFromStrength(t *testing.T) {
+	settings := benchmarkSettings()
+	teams := []Team{{ID: 1, Name: "A", Strength: 50}, {ID: 2, Name: "B", Strength: 75}}
+	matches := []Match{playedMatch(1, 2, -1, 0)}
+
+	if _, _, err := replayRatings(teams, matches, settings.Ratings); err == nil {
+		t.Fatal("replayed a match with negative goals")
+	}
+	for _, team := range computeStandings(teams, matches, settings) {
+		if want := roundRating(ratingFromStrength(team.Strength)); team.Rating != want {
+			t.Errorf("%s rated %v after a broken replay, want %v from its strength", team.Name, team.Rating, want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+)
+
+// errMatchNotFound is returned when a match id does not exist in the repository
+var errMatchNotFound = errors.New("match not found")
+
+// errTeamNotFound is returned when a team id does not exist in the repository
+var errTeamNotFound = errors.New("team not found")
+
+// errDeductionNotFound is returned when a points deduction id does not exist in the repository
+var errDeductionNotFound = errors.New("deduction not found")
+
+// errLeagueNotFound is returned when a league id does not exist in the store
+var errLeagueNotFound = errors.New("league not found")
+
+// errSeasonNotFound is returned when a season id does not exist in the store or belongs to another league
+var errSeasonNotFound = errors.New("season not found")
+
+// errSeasonArchived is returned when writing to a season that has been archived
+var errSeasonArchived = errors.New("season is archived and can no longer be changed")
+
+// errSeasonNotFinished is returned when rolling a season over before all of its matches are played
+var errSeasonNotFinished = errors.New("season has unplayed matches")
+
+// errTeamNameTaken is returned when creating or renaming a team to the name of another team
+var errTeamNameTaken = errors.New("team name is already taken")
+
+// errTeamHasResults is returned when deleting a team that has played matches, its results belong to the history of its seasons
+var errTeamHasResults = errors.New("team has played matches and cannot be deleted")
+
+// errSeasonEnded is returned when playing a week after every match of the season has been played
+var errSeasonEnded = errors.New("Season has ended")
+
+// errNoFixtures is returned when playing a week of a season without any fixtures
+var errNoFixtures = errors.New("No fixtures scheduled")
+
+// errInvalidInput marks errors caused by a request that can never succeed as sent
+var errInvalidInput = errors.New("invalid input")
+
+// LeagueRepository stores the teams and matches of one season behind TeamService and MatchService.
+// Teams are returned as stored, standings are computed by the services from the matches.
+type LeagueRepository interface {
+	Teams() ([]Team, error)
+	Matches() ([]Match, error)
+	Match(id int) (Match, error)
+
+	SaveMatchResult(id, homeGoals, awayGoals int) error
+	SaveKnockoutResult(m Match) error // stores the score with extra time and penalties of a cup match
+	ReplaceMatches(matches []Match) ([]Match, error)
+	AddMatches(matches []Match) ([]Match, error)
+	RescheduleMatch(id, week int, postponed bool) error
+	DeleteMatch(id int) error
+	SaveStandings(teams []Team) error // overwrites the counters and the fair play points of the season's teams
+
+	// Ratings is the stored rating history ordered by team and week, SaveRatings replaces all of it
+	Ratings() ([]RatingPoint, error)
+	SaveRatings(history []RatingPoint) error
+
+	// Deductions is the ledger of administrative points deductions ordered by date, Teams includes their total per team
+	Deductions() ([]Deduction, error)
+	AddDeduction(d Deduction) (Deduction, error)
+	DeleteDeduction(id int) error
+
+	// Atomic runs fn with exclusive write access to the season, failing with errSeasonArchived once it is archived.
+	// Every change made through the given repository is discarded if fn returns an error.
+	Atomic(fn func(repo LeagueRepository) error) error
+}
+
+// LeagueStore holds every league, season and team, and hands out a LeagueRepository scoped to one season.
+// Teams exist once and take part in seasons, their counters are kept per season.
+type LeagueStore interface {
+	Leagues() ([]League, error)
+	League(id int) (League, error)
+	// LockLeagues serializes the writers starting seasons of the leagues, every league when no id is given.
+	// It must be the first statement of the transaction, before anything is read.
+	LockLeagues(ids ...int) error
+	CreateLeague(l League) (League, error)
+	SaveLeague(l League) error // overwrites the name, the match engine and the division settings
+
+	Seasons(leagueID int) ([]Season, error)
+	Season(id int) (Season, error)
+	CreateSeason(leagueID int, name string) (Season, error)
+	ArchiveSeasons(leagueID int) error // archives every active season of the league
+	ArchiveSeason(id int) error
+	AddSeasonTeams(seasonID int, teams []Team) error // enters the teams with their seed and group
+
+	AllTeams() ([]Team, error) // every team with its name and strength, without counters
+	CreateTeam(name string, strength int) (Team, error)
+	SaveTeam(t Team) error                   // overwrites the name and strength, and the team names stored on its matches
+	HasPlayedMatches(teamID int) (bool, error)
+	DeleteTeam(id int) error // removes the team with its season entries, fixtures, deductions, ratings and fitted parameters
+
+	// FittedModel is the model the fitted engine plays with, SaveFittedModel replaces it
+	FittedModel() (FittedModel, error)
+	SaveFittedModel(m FittedModel) error
+
+	ForSeason(seasonID int) LeagueRepository
+
+	// Atomic runs fn in a single transaction over the whole store
+	Atomic(fn func(store LeagueStore) error) error
+}
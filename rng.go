@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newRNG creates the random source for one request, the same seed always replays the same simulation
+func newRNG(seed int64) *rand.Rand {
+	return rand.New(rand.NewSource(seed))
+}
+
+// maxRequestSimulations bounds the ?simulations= parameter, like the simulations setting of the config
+const maxRequestSimulations = 1000000
+
+// requestSimulations reads the optional ?simulations= query parameter, 0 means the configured count
+func requestSimulations(c *gin.Context) (int, error) {
+	value, ok := c.GetQuery("simulations")
+	if !ok || value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > maxRequestSimulations {
+		return 0, fmt.Errorf("simulations must be an integer between 1 and %d, got %q", maxRequestSimulations, value)
+	}
+	return n, nil
+}
+
+// requestSeed reads the optional ?seed= query parameter, or picks a fresh seed to echo back when it is missing
+func requestSeed(c *gin.Context) (int64, error) {
+	value, ok := c.GetQuery("seed")
+	if !ok || value == "" {
+		return rand.Int63(), nil
+	}
+	seed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("seed must be an integer, got %q", value)
+	}
+	return seed, nil
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PlayoffMatch is a single-leg promotion playoff tie, a draw goes to extra time and then to penalties
+type PlayoffMatch struct {
+	Round         int    `json:"round"`
+	HomeTeamID    int    `json:"home_team_id"`
+	AwayTeamID    int    `json:"away_team_id"`
+	NameHome      string `json:"name_home"`
+	NameAway      string `json:"name_away"`
+	HomeGoals     int    `json:"home_goals"`
+	AwayGoals     int    `json:"away_goals"`
+	ExtraTime     bool   `json:"extra_time,omitempty"` // goals include extra time
+	Penalties     bool   `json:"decided_on_penalties"`
+	HomePenalties *int   `json:"home_penalties,omitempty"`
+	AwayPenalties *int   `json:"away_penalties,omitempty"`
+	WinnerID      int    `json:"winner_id"`
+}
+
+// DivisionRollover reports how one division ended its season and which teams it exchanged with its neighbours
+type DivisionRollover struct {
+	League         League         `json:"league"`
+	ArchivedSeason Season         `json:"archived_season"`
+	FinalTable     []Team         `json:"final_table"`
+	Promoted       []Team         `json:"promoted"`          // teams moving up to the division above
+	Relegated      []Team         `json:"relegated"`         // teams moving down to the division below
+	Playoff        []PlayoffMatch `json:"playoff,omitempty"` // ties played for the last promotion place
+	NewSeason      Season         `json:"new_season"`
+}
+
+// Rollover ends the season of every division linked to a league and starts the next one.
+// Each division needs an active season whose matches are all played; the final tables are snapshotted on the archived seasons,
+// teams are promoted and relegated, and every division starts a fresh season with zeroed counters and new fixtures.
+func (s *MyLeagueService) Rollover(leagueID int, rng *rand.Rand) ([]DivisionRollover, error) {
+	var divisions []DivisionRollover
+	err := s.store.Atomic(func(store LeagueStore) error {
+		// The divisions are only known after reading the leagues, so every league is locked against concurrent season starts
+		if err := store.LockLeagues(); err != nil {
+			return err
+		}
+		chain, err := divisionChain(store, leagueID)
+		if err != nil {
+			return err
+		}
+
+		// Snapshot the final table of every division
+		divisions = make([]DivisionRollover, len(chain))
+		seasonCounts := make([]int, len(chain))
+		doubleRoundRobins := make([]bool, len(chain))
+		for i, league := range chain {
+			seasons, err := store.Seasons(league.ID)
+			if err != nil {
+				return err
+			}
+			if len(seasons) == 0 {
+				return fmt.Errorf("%w: %s has no season", errSeasonNotFound, league.Name)
+			}
+			current := currentSeason(seasons)
+			if current.Status != seasonActive {
+				return fmt.Errorf("%w: %s has no active season", errSeasonArchived, league.Name)
+			}
+
+			repo := store.ForSeason(current.ID)
+			matches, err := repo.Matches()
+			if err != nil {
+				return err
+			}
+			if slices.ContainsFunc(matches, func(m Match) bool { return !m.Played }) {
+				return fmt.Errorf("%w: %s %s", errSeasonNotFinished, league.Name, current.Name)
+			}
+			table, err := syncStandings(repo, s.settings)
+			if err != nil {
+				return err
+			}
+
+			current.Status = seasonArchived
+			divisions[i] = DivisionRollover{League: league, ArchivedSeason: current, FinalTable: table, Promoted: []Team{}, Relegated: []Team{}}
+			seasonCounts[i] = len(seasons)
+			// Keep the format of the season that just ended, counting the round-robin matches inside every group
+			groups := max(league.Groups, 1)
+			size := len(table) / groups
+			roundRobin := slices.DeleteFunc(slices.Clone(matches), func(m Match) bool { return m.Round > 0 })
+			doubleRoundRobins[i] = len(roundRobin) > groups*size*(size-1)/2
+		}
+
+		// Swap teams between each division and the one below it
+		for i := 0; i+1 < len(divisions); i++ {
+			upper, lower := &divisions[i], &divisions[i+1]
+			promoted, relegated, playoff, err := exchangeTeams(upper.League, upper.FinalTable, lower.FinalTable, s.settings.forLeague(lower.League).Engine, rng)
+			if err != nil {
+				return err
+			}
+			lower.Promoted, lower.Playoff = promoted, playoff
+			upper.Relegated = relegated
+		}
+
+		// Archive the old seasons and start the new ones
+		for i := range divisions {
+			d := &divisions[i]
+			var teamIDs []int
+			for _, t := range d.FinalTable {
+				moved := func(m Team) bool { return m.ID == t.ID }
+				if !slices.ContainsFunc(d.Promoted, moved) && !slices.ContainsFunc(d.Relegated, moved) {
+					teamIDs = append(teamIDs, t.ID)
+				}
+			}
+			if i > 0 {
+				for _, t := range divisions[i-1].Relegated {
+					teamIDs = append(teamIDs, t.ID)
+				}
+			}
+			if i+1 < len(divisions) {
+				for _, t := range divisions[i+1].Promoted {
+					teamIDs = append(teamIDs, t.ID)
+				}
+			}
+
+			if err := store.ArchiveSeasons(d.League.ID); err != nil {
+				return err
+			}
+			d.NewSeason, err = startSeason(store, d.League, fmt.Sprintf("Season %d", seasonCounts[i]+1), teamIDs, doubleRoundRobins[i], rng)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return divisions, nil
+}
+
+// divisionChain returns every division linked to a league, from the top division down
+func divisionChain(store LeagueStore, leagueID int) ([]League, error) {
+	leagues, err := store.Leagues()
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]League, len(leagues))
+	above := make(map[int]int, len(leagues))
+	for _, l := range leagues {
+		byID[l.ID] = l
+		if l.DivisionBelowID != nil {
+			above[*l.DivisionBelowID] = l.ID
+		}
+	}
+	top, ok := byID[leagueID]
+	if !ok {
+		return nil, errLeagueNotFound
+	}
+
+	// Climb to the top division, then walk down again
+	for id, ok := above[top.ID]; ok; id, ok = above[top.ID] {
+		top = byID[id]
+		if top.ID == leagueID {
+			return nil, fmt.Errorf("%w: the divisions of league %d form a cycle", errInvalidInput, leagueID)
+		}
+	}
+	chain := []League{top}
+	for l := top; l.DivisionBelowID != nil; {
+		l = byID[*l.DivisionBelowID]
+		if len(chain) == len(leagues) {
+			return nil, fmt.Errorf("%w: the divisions of league %d form a cycle", errInvalidInput, leagueID)
+		}
+		chain = append(chain, l)
+	}
+	return chain, nil
+}
+
+// exchangeTeams picks the teams promoted out of the lower table and relegated out of the upper table.
+// With playoffs the upper division relegates one more team to make room for the playoff winner.
+func exchangeTeams(upper League, upperTable, lowerTable []Team, engine MatchEngine, rng *rand.Rand) ([]Team, []Team, []PlayoffMatch, error) {
+	automatic, playoffPlaces := upper.PromotionPlaces, upper.PlayoffPlaces
+	down := automatic
+	if playoffPlaces > 0 {
+		down++
+	}
+	if automatic+playoffPlaces > len(lowerTable) || down > len(upperTable) {
+		return nil, nil, nil, fmt.Errorf("%w: %s swaps %d teams and has %d playoff places, more than its divisions hold", errInvalidInput, upper.Name, automatic, playoffPlaces)
+	}
+
+	promoted := slices.Clone(lowerTable[:automatic])
+	var playoff []PlayoffMatch
+	if playoffPlaces > 0 {
+		var winner Team
+		winner, playoff = playPlayoff(lowerTable[automatic:automatic+playoffPlaces], engine, rng)
+		promoted = append(promoted, winner)
+	}
+	relegated := slices.Clone(upperTable[len(upperTable)-down:])
+	return promoted, relegated, playoff, nil
+}
+
+// playPlayoff runs a seeded single-leg knockout, the best placed team hosts the worst placed one in the first round.
+// A match level after 90 minutes goes to extra time and then to a penalty shootout, like a cup tie.
+func playPlayoff(seeds []Team, engine MatchEngine, rng *rand.Rand) (Team, []PlayoffMatch) {
+	var matches []PlayoffMatch
+	for round := 1; len(seeds) > 1; round++ {
+		var winners []Team
+		for i := 0; i < len(seeds)/2; i++ {
+			home, away := seeds[i], seeds[len(seeds)-1-i]
+			m := PlayoffMatch{
+				Round:      round,
+				HomeTeamID: home.ID,
+				AwayTeamID: away.ID,
+				NameHome:   home.Name,
+				NameAway:   away.Name,
+			}
+			m.HomeGoals, m.AwayGoals = engine.SimulateMatch(rng, home, away)
+			if m.HomeGoals == m.AwayGoals {
+				extraHome, extraAway := simulateExtraTime(rng, engine, home, away)
+				m.HomeGoals, m.AwayGoals, m.ExtraTime = m.HomeGoals+extraHome, m.AwayGoals+extraAway, true
+			}
+			winner := home
+			if m.HomeGoals == m.AwayGoals {
+				homePenalties, awayPenalties := simulateShootout(rng, home, away)
+				m.Penalties, m.HomePenalties, m.AwayPenalties = true, &homePenalties, &awayPenalties
+				if awayPenalties > homePenalties {
+					winner = away
+				}
+			} else if m.AwayGoals > m.HomeGoals {
+				winner = away
+			}
+			m.WinnerID = winner.ID
+			matches = append(matches, m)
+			winners = append(winners, winner)
+		}
+		seeds = winners
+	}
+	return seeds[0], matches
+}
+
+// RolloverHandler handles the request to end the season of a league and its linked divisions and start the next one.
+// Without a league in the path it rolls over the divisions of the given default league.
+func RolloverHandler(leagueService LeagueService, defaultLeagueID int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		leagueID := defaultLeagueID
+		if c.Param("league_id") != "" {
+			var err error
+			if leagueID, err = pathID(c, "league_id"); err != nil {
+				c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+		}
+		seed, err := requestSeed(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		divisions, err := leagueService.Rollover(leagueID, newRNG(seed))
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message":   "Season rolled over successfully",
+			"divisions": divisions,
+			"seed":      seed,
+		})
+	}
+}
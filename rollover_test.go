@@ -0,0 +1,57 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// goallessEngine expects and plays no goals at all, so every match goes to penalties
+type goallessEngine struct{}
+
+func (goallessEngine) Name() string                                     { return "goalless" }
+func (goallessEngine) ExpectedGoals(home, away Team) (float64, float64) { return 0, 0 }
+func (goallessEngine) ScoreProbabilities(home, away Team) [][]float64   { return [][]float64{{1}} }
+func (goallessEngine) SimulateMatch(rng *rand.Rand, home, away Team) (int, int) {
+	return 0, 0
+}
+
+func TestPlayoffDrawsGoToExtraTimeAndPenalties(t *testing.T) {
+	seeds := []Team{
+		{ID: 1, Name: "Third", Strength: 50},
+		{ID: 2, Name: "Fourth", Strength: 50},
+		{ID: 3, Name: "Fifth", Strength: 50},
+		{ID: 4, Name: "Sixth", Strength: 50},
+	}
+	wins := make(map[int]int)
+	for seed := int64(1); seed <= 200; seed++ {
+		winner, matches := playPlayoff(seeds, goallessEngine{}, newRNG(seed))
+		if len(matches) != 3 {
+			t.Fatalf("seed %d: %d playoff matches, want 3", seed, len(matches))
+		}
+		for _, m := range matches {
+			if !m.ExtraTime || !m.Penalties || m.HomePenalties == nil || m.AwayPenalties == nil {
+				t.Fatalf("seed %d: goalless match not settled by extra time and penalties: %+v", seed, m)
+			}
+			if *m.HomePenalties == *m.AwayPenalties {
+				t.Fatalf("seed %d: shootout ended level %d-%d", seed, *m.HomePenalties, *m.AwayPenalties)
+			}
+			want := m.HomeTeamID
+			if *m.AwayPenalties > *m.HomePenalties {
+				want = m.AwayTeamID
+			}
+			if m.WinnerID != want {
+				t.Errorf("seed %d: shootout %d-%d won by team %d, want %d", seed, *m.HomePenalties, *m.AwayPenalties, m.WinnerID, want)
+			}
+		}
+		if final := matches[2]; winner.ID != final.WinnerID {
+			t.Errorf("seed %d: promoted team %d is not the winner of the final %d", seed, winner.ID, final.WinnerID)
+		}
+		wins[winner.ID]++
+	}
+	// Equal teams on penalties: every one of them should go up now and then
+	for _, team := range seeds {
+		if wins[team.ID] < 20 {
+			t.Errorf("%s won %d of 200 playoffs between equal teams", team.Name, wins[team.ID])
+		}
+	}
+}
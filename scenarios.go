@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScenarioResult is a hypothetical result for one unplayed match, either an exact score or just an outcome
+type ScenarioResult struct {
+	MatchID   int    `json:"match_id"`
+	HomeGoals *int   `json:"home_goals"`
+	AwayGoals *int   `json:"away_goals"`
+	Outcome   string `json:"outcome"` // home, draw or away, used when no score is given
+}
+
+// outcomeScores are the scores an outcome without goals is counted as
+var outcomeScores = map[string][2]int{
+	"home": {1, 0},
+	"draw": {0, 0},
+	"away": {0, 1},
+}
+
+// score returns the goals the hypothetical result is counted with
+func (r ScenarioResult) score() (int, int, error) {
+	if r.HomeGoals != nil || r.AwayGoals != nil {
+		if r.HomeGoals == nil || r.AwayGoals == nil {
+			return 0, 0, fmt.Errorf("%w: match %d needs both home_goals and away_goals", errInvalidInput, r.MatchID)
+		}
+		if *r.HomeGoals < 0 || *r.AwayGoals < 0 {
+			return 0, 0, fmt.Errorf("%w: match %d has negative goals", errInvalidInput, r.MatchID)
+		}
+		return *r.HomeGoals, *r.AwayGoals, nil
+	}
+	goals, ok := outcomeScores[r.Outcome]
+	if !ok {
+		return 0, 0, fmt.Errorf("%w: match %d needs a score or an outcome of home, draw or away", errInvalidInput, r.MatchID)
+	}
+	return goals[0], goals[1], nil
+}
+
+// applyScenario returns a copy of matches with the hypothetical results played
+func applyScenario(matches []Match, results []ScenarioResult) ([]Match, error) {
+	scenario := cloneMatches(matches)
+	index := make(map[int]int, len(scenario))
+	for i, m := range scenario {
+		index[m.ID] = i
+	}
+
+	seen := make(map[int]bool, len(results))
+	for _, r := range results {
+		i, ok := index[r.MatchID]
+		if !ok {
+			return nil, fmt.Errorf("%w: %d", errMatchNotFound, r.MatchID)
+		}
+		if seen[r.MatchID] {
+			return nil, fmt.Errorf("%w: match %d is given more than once", errInvalidInput, r.MatchID)
+		}
+		seen[r.MatchID] = true
+		if scenario[i].Played {
+			return nil, fmt.Errorf("%w: match %d has already been played", errInvalidInput, r.MatchID)
+		}
+
+		homeGoals, awayGoals, err := r.score()
+		if err != nil {
+			return nil, err
+		}
+		scenario[i].HomeGoals, scenario[i].AwayGoals, scenario[i].Played = &homeGoals, &awayGoals, true
+	}
+	return scenario, nil
+}
+
+// ScenarioHandler projects the standings and championship probabilities for hypothetical results without storing them
+func ScenarioHandler(teamService TeamService, matchService MatchService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Results []ScenarioResult `json:"results"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+		seed, err := requestSeed(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		simulations, err := requestSimulations(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		teams, err := teamService.GetTeams()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		matches, err := matchService.GetMatches()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		scenario, err := applyScenario(matches, req.Results)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		// Everything below works on the copied matches, the repository is never written
+		settings := matchService.Settings()
+		if simulations > 0 {
+			settings.Simulations = simulations
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"standings":                  computeStandings(teams, scenario, settings),
+			"championship_probabilities": titleProbabilities(teams, scenario, settings, newRNG(seed)),
+			"seed":                       seed,
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestScenarioLeavesTheSeasonUntouched(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	leagueService := &MyLeagueService{store: newMemoryStore(sampleTeams()), settings: benchmarkSettings()}
+	services, err := leagueService.SeasonServices(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := newRNG(1)
+	for range 3 {
+		if _, _, err := services.matches.PlayWeek(rng); err != nil {
+			t.Fatal(err)
+		}
+	}
+	router := gin.New()
+	router.POST("/scenarios", ScenarioHandler(services.teams, services.matches))
+
+	repo := services.matches.repo
+	snapshot := func() ([]Team, []Match) {
+		teams, err := repo.Teams()
+		if err != nil {
+			t.Fatal(err)
+		}
+		matches, err := repo.Matches()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return teams, matches
+	}
+	teamsBefore, matchesBefore := snapshot()
+
+	// Every remaining match ends in a big away win
+	var results []string
+	for _, m := range matchesBefore {
+		if !m.Played {
+			results = append(results, fmt.Sprintf(`{"match_id":%d,"home_goals":0,"away_goals":4}`, m.ID))
+		}
+	}
+	w := httptest.NewRecorder()
+	body := `{"results":[` + strings.Join(results, ",") + `]}`
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/scenarios?seed=1&simulations=100", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Standings []Team `json:"standings"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	for _, team := range response.Standings {
+		if played := team.Wins + team.Draws + team.Losses; played != 6 {
+			t.Errorf("%s has played %d matches in the scenario, want all 6", team.Name, played)
+		}
+	}
+
+	teamsAfter, matchesAfter := snapshot()
+	if !reflect.DeepEqual(teamsBefore, teamsAfter) {
+		t.Errorf("the scenario changed the stored table:\n%+v\n%+v", teamsBefore, teamsAfter)
+	}
+	if !reflect.DeepEqual(matchesBefore, matchesAfter) {
+		t.Errorf("the scenario changed the stored matches")
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AddFixture schedules a new match between two teams of the season
+func (s *MyMatchService) AddFixture(homeTeamID, awayTeamID, week int) (Match, error) {
+	var match Match
+	err := s.repo.Atomic(func(repo LeagueRepository) error {
+		teams, err := repo.Teams()
+		if err != nil {
+			return err
+		}
+		byID := teamsByID(teams)
+		home, ok := byID[homeTeamID]
+		if !ok {
+			return fmt.Errorf("%w: %d", errTeamNotFound, homeTeamID)
+		}
+		away, ok := byID[awayTeamID]
+		if !ok {
+			return fmt.Errorf("%w: %d", errTeamNotFound, awayTeamID)
+		}
+		if err := s.checkWeek(week); err != nil {
+			return err
+		}
+		if err := checkTeamsFree(repo, week, 0, home.ID, away.ID); err != nil {
+			return err
+		}
+
+		added, err := repo.AddMatches([]Match{{
+			NameHome:   home.Name,
+			NameAway:   away.Name,
+			HomeTeamID: home.ID,
+			AwayTeamID: away.ID,
+			Week:       week,
+		}})
+		if err != nil {
+			return err
+		}
+		match = added[0]
+		return nil
+	})
+	if err != nil {
+		return Match{}, err
+	}
+	return match, nil
+}
+
+// RescheduleMatch moves an unplayed match to another week, a postponed match becomes a regular fixture again.
+// A week that was already played is played again next, before the weeks after it.
+func (s *MyMatchService) RescheduleMatch(id, week int) (Match, error) {
+	var match Match
+	err := s.repo.Atomic(func(repo LeagueRepository) error {
+		var err error
+		match, err = unplayedMatch(repo, id)
+		if err != nil {
+			return err
+		}
+		if err := s.checkWeek(week); err != nil {
+			return err
+		}
+		if err := checkTeamsFree(repo, week, match.ID, match.HomeTeamID, match.AwayTeamID); err != nil {
+			return err
+		}
+		match.Week, match.Postponed = week, false
+		return repo.RescheduleMatch(id, match.Week, match.Postponed)
+	})
+	if err != nil {
+		return Match{}, err
+	}
+	return match, nil
+}
+
+// PostponeMatch takes an unplayed match off its week. It keeps the week it was due in and stays a game in hand,
+// still counted by the simulations, until it is rescheduled.
+func (s *MyMatchService) PostponeMatch(id int) (Match, error) {
+	var match Match
+	err := s.repo.Atomic(func(repo LeagueRepository) error {
+		var err error
+		match, err = unplayedMatch(repo, id)
+		if err != nil {
+			return err
+		}
+		match.Postponed = true
+		return repo.RescheduleMatch(id, match.Week, match.Postponed)
+	})
+	if err != nil {
+		return Match{}, err
+	}
+	return match, nil
+}
+
+// CancelMatch removes an unplayed match from the schedule
+func (s *MyMatchService) CancelMatch(id int) error {
+	return s.repo.Atomic(func(repo LeagueRepository) error {
+		if _, err := unplayedMatch(repo, id); err != nil {
+			return err
+		}
+		return repo.DeleteMatch(id)
+	})
+}
+
+// checkWeek refuses a week after the configured season length, whose matches would never be played
+func (s *MyMatchService) checkWeek(week int) error {
+	if s.settings.SeasonLength > 0 && week > s.settings.SeasonLength {
+		return fmt.Errorf("%w: the season is limited to %d weeks", errInvalidInput, s.settings.SeasonLength)
+	}
+	return nil
+}
+
+// checkTeamsFree refuses a week in which either team already has a match other than the one being moved.
+// Postponed matches are off their week and do not count.
+func checkTeamsFree(repo LeagueRepository, week, matchID int, teamIDs ...int) error {
+	matches, err := repo.Matches()
+	if err != nil {
+		return err
+	}
+	for _, m := range matches {
+		if m.Week != week || m.ID == matchID || m.Postponed {
+			continue
+		}
+		for _, id := range teamIDs {
+			if m.HomeTeamID == id || m.AwayTeamID == id {
+				return fmt.Errorf("%w: team %d already plays match %d in week %d", errInvalidInput, id, m.ID, week)
+			}
+		}
+	}
+	return nil
+}
+
+// unplayedMatch reads a match that may still be moved, the results of played matches are changed with /change-match-result
+func unplayedMatch(repo LeagueRepository, id int) (Match, error) {
+	match, err := repo.Match(id)
+	if err != nil {
+		return Match{}, err
+	}
+	if match.Played {
+		return Match{}, fmt.Errorf("%w: match %d has already been played", errInvalidInput, id)
+	}
+	return match, nil
+}
+
+// AddFixtureHandler handles the request to schedule a new match
+func AddFixtureHandler(matchService MatchService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		type AddFixtureRequest struct {
+			HomeTeamID int `json:"home_team_id"`
+			AwayTeamID int `json:"away_team_id"`
+			Week       int `json:"week"`
+		}
+		var req AddFixtureRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+		if req.HomeTeamID == req.AwayTeamID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "a team cannot play itself"})
+			return
+		}
+		if req.Week < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "week must be a positive number"})
+			return
+		}
+
+		match, err := matchService.AddFixture(req.HomeTeamID, req.AwayTeamID, req.Week)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"match": match})
+	}
+}
+
+// RescheduleMatchHandler handles the request to move an unplayed match to another week
+func RescheduleMatchHandler(matchService MatchService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := pathID(c, "id")
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		type RescheduleMatchRequest struct {
+			Week int `json:"week"`
+		}
+		var req RescheduleMatchRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+		if req.Week < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "week must be a positive number"})
+			return
+		}
+
+		match, err := matchService.RescheduleMatch(id, req.Week)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"match": match})
+	}
+}
+
+// PostponeMatchHandler handles the request to postpone an unplayed match until it is rescheduled
+func PostponeMatchHandler(matchService MatchService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := pathID(c, "id")
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		match, err := matchService.PostponeMatch(id)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"match": match})
+	}
+}
+
+// CancelMatchHandler handles the request to remove an unplayed match from the schedule
+func CancelMatchHandler(matchService MatchService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := pathID(c, "id")
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		if err := matchService.CancelMatch(id); err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Match cancelled successfully"})
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFixturesCannotBookATeamTwiceInAWeek(t *testing.T) {
+	leagueService := &MyLeagueService{store: newMemoryStore(sampleTeams()), settings: benchmarkSettings()}
+	services, err := leagueService.SeasonServices(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	matchService := services.matches
+	matches, _ := matchService.GetMatches()
+	inWeek := func(week int) []Match {
+		var found []Match
+		for _, m := range matches {
+			if m.Week == week {
+				found = append(found, m)
+			}
+		}
+		return found
+	}
+
+	// Every team of the four plays in every week of the double round-robin
+	if _, err := matchService.AddFixture(1, 2, 1); !errors.Is(err, errInvalidInput) {
+		t.Errorf("adding a fixture to a full week gave %v, want %v", err, errInvalidInput)
+	}
+	if _, err := matchService.AddFixture(1, 2, 7); err != nil {
+		t.Errorf("adding a fixture to a free week: %v", err)
+	}
+
+	moved := inWeek(1)[0]
+	if _, err := matchService.RescheduleMatch(moved.ID, 2); !errors.Is(err, errInvalidInput) {
+		t.Errorf("moving match %d into a full week gave %v, want %v", moved.ID, err, errInvalidInput)
+	}
+	if _, err := matchService.RescheduleMatch(moved.ID, 1); err != nil {
+		t.Errorf("keeping match %d in its own week: %v", moved.ID, err)
+	}
+
+	// Postponed matches are off their week and free their teams
+	for _, m := range inWeek(2) {
+		if _, err := matchService.PostponeMatch(m.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := matchService.RescheduleMatch(moved.ID, 2); err != nil {
+		t.Errorf("moving match %d into a week of postponed matches: %v", moved.ID, err)
+	}
+}
@@ -1,12 +1,71 @@
 package main
 
 import (
+	"fmt"
 	"math/rand"
 )
 
-// Simulate teams' goals by looking at the expected goal values returned from simulateMatch function
-func simulateGoals(expected float64) int {
-    prob := rand.Float64()
+// maxMatrixGoals is the highest score per side kept in a scoreline probability matrix
+const maxMatrixGoals = 10
+
+// MatchEngine simulates the score of a match between two teams
+type MatchEngine interface {
+	// Name identifies the engine in the configuration
+	Name() string
+	// ExpectedGoals returns the mean number of goals of the home and away team
+	ExpectedGoals(home, away Team) (float64, float64)
+	// ScoreProbabilities returns P(home goals = i, away goals = j) for i, j <= maxMatrixGoals
+	ScoreProbabilities(home, away Team) [][]float64
+	// SimulateMatch samples a scoreline, drawing every random number from rng
+	SimulateMatch(rng *rand.Rand, home, away Team) (int, int)
+}
+
+// matchEngineByName returns the engine selected in the configuration
+func matchEngineByName(name string) (MatchEngine, error) {
+	switch name {
+	case "legacy":
+		return legacyEngine{}, nil
+	case "poisson":
+		return poissonEngine{}, nil
+	case "dixon-coles":
+		return dixonColesEngine{rho: defaultDixonColesRho}, nil
+	case "fitted":
+		return &fittedEngine{}, nil
+	}
+	return nil, fmt.Errorf("match engine must be legacy, poisson, dixon-coles or fitted, got %q", name)
+}
+
+// legacyEngine is the original bucketed model the league has always been simulated with
+type legacyEngine struct{}
+
+// Name identifies the engine in the configuration
+func (legacyEngine) Name() string { return "legacy" }
+
+// ExpectedGoals splits the goals by strength ratio
+func (legacyEngine) ExpectedGoals(home, away Team) (float64, float64) {
+	total := home.power() + away.power()
+	//Premier League statistics show that home teams average 1.6 goals, while away teams average 1.2
+	//That's why I use coefficients 2.0 and 1.8 for calculations below to give the advantage to the Home Team
+	expectedHome := home.power() / total * 2.0 //Home Team has the advantage
+	expectedAway := away.power() / total * 1.8
+	return expectedHome, expectedAway
+}
+
+// Simulate a match between two teams by looking at their strengths
+func (e legacyEngine) SimulateMatch(rng *rand.Rand, home, away Team) (int, int) {
+	expectedHome, expectedAway := e.ExpectedGoals(home, away)
+	return simulateGoals(rng, expectedHome), simulateGoals(rng, expectedAway)
+}
+
+// ScoreProbabilities combines the bucket probabilities of simulateGoals for both sides
+func (e legacyEngine) ScoreProbabilities(home, away Team) [][]float64 {
+	expectedHome, expectedAway := e.ExpectedGoals(home, away)
+	return outerProduct(legacyGoalDistribution(expectedHome), legacyGoalDistribution(expectedAway))
+}
+
+// Simulate teams' goals by looking at the expected goal values returned from ExpectedGoals
+func simulateGoals(rng *rand.Rand, expected float64) int {
+    prob := rng.Float64()
 
     switch {
     case prob < 0.4: //Highest probability seperated for expected results case
@@ -16,18 +75,81 @@ func simulateGoals(expected float64) int {
     case prob < 0.8:
         return int(expected) + 2
 	case prob < 0.9:
-		return int(expected) + 3	
+		return int(expected) + 3
     default: //To ensure that unexpected results can also occur
-        return int(expected) + rand.Intn(5)
+        return int(expected) + rng.Intn(5)
     }
 }
 
-// Simulate a match between two teams by looking at their strengths
-func simulateMatch(homeStrength, awayStrength int) (int, int) {
-	total := float64(homeStrength + awayStrength)
-	//Premier League statistics show that home teams average 1.6 goals, while away teams average 1.2
-	//That's why I use coefficients 2.0 and 1.8 for calculations below to give the advantage to the Home Team
-	expectedHome := float64(homeStrength) / total * 2.0 //Home Team has the advantage 
-    expectedAway := float64(awayStrength) / total * 1.8 
-    return simulateGoals(expectedHome), simulateGoals(expectedAway)
-}
\ No newline at end of file
+// legacyGoalDistribution returns the exact distribution sampled by simulateGoals
+func legacyGoalDistribution(expected float64) []float64 {
+	dist := make([]float64, maxMatrixGoals+1)
+	base := int(expected)
+	add := func(goals int, p float64) {
+		if goals <= maxMatrixGoals {
+			dist[goals] += p
+		}
+	}
+	add(base, 0.4)
+	add(base+1, 0.25)
+	add(base+2, 0.15)
+	add(base+3, 0.1)
+	// The last 10% is spread evenly over base .. base+4
+	for extra := 0; extra < 5; extra++ {
+		add(base+extra, 0.1/5)
+	}
+	return dist
+}
+
+// outerProduct builds the scoreline matrix of two independent goal distributions
+func outerProduct(home, away []float64) [][]float64 {
+	matrix := make([][]float64, len(home))
+	for i := range home {
+		matrix[i] = make([]float64, len(away))
+		for j := range away {
+			matrix[i][j] = home[i] * away[j]
+		}
+	}
+	return matrix
+}
+
+// extraTimeShare is the length of extra time relative to a full match
+const extraTimeShare = 30.0 / 90.0
+
+// penaltyConversion is the share of shootout kicks that are scored between two equal teams
+const penaltyConversion = 0.75
+
+// simulateExtraTime samples the goals of 30 minutes of extra time from Poisson distributions
+// at a third of the goals the engine expects over a full match
+func simulateExtraTime(rng *rand.Rand, engine MatchEngine, home, away Team) (int, int) {
+	expectedHome, expectedAway := engine.ExpectedGoals(home, away)
+	return samplePoisson(rng, expectedHome*extraTimeShare), samplePoisson(rng, expectedAway*extraTimeShare)
+}
+
+// simulateShootout plays a penalty shootout of five kicks each followed by sudden death.
+// The stronger team converts slightly more often, the shootout stops as soon as one side cannot be caught.
+func simulateShootout(rng *rand.Rand, home, away Team) (int, int) {
+	share := home.power() / (home.power() + away.power())
+	homeConversion := penaltyConversion + 0.1*(share-0.5)
+	awayConversion := penaltyConversion - 0.1*(share-0.5)
+
+	homeScored, awayScored := 0, 0
+	for kick := 1; ; kick++ {
+		if rng.Float64() < homeConversion {
+			homeScored++
+		}
+		if kick <= 5 && (homeScored > awayScored+6-kick || awayScored > homeScored+5-kick) {
+			break
+		}
+		if rng.Float64() < awayConversion {
+			awayScored++
+		}
+		if kick < 5 && (homeScored > awayScored+5-kick || awayScored > homeScored+5-kick) {
+			break
+		}
+		if kick >= 5 && homeScored != awayScored {
+			break
+		}
+	}
+	return homeScored, awayScored
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// sqlRepository implements LeagueRepository for one season on top of the season_teams and matches tables in MySQL or SQLite
+type sqlRepository struct {
+	db       *sql.DB // nil while running inside Atomic
+	q        queryer
+	dialect  string
+	seasonID int
+}
+
+// Teams reads the teams of the season as stored, including their counters and the total of their points deductions
+func (r *sqlRepository) Teams() ([]Team, error) {
+	rows, err := r.q.Query(`SELECT t.id, t.name, t.strength, st.points, st.goals_for, st.goals_against, st.goal_diff, st.wins, st.draws, st.losses,
+							   st.fair_play_points, COALESCE(d.points, 0), st.seed, st.group_no
+						    FROM season_teams st
+						    JOIN teams t ON t.id = st.team_id
+						    LEFT JOIN (SELECT team_id, SUM(points) AS points FROM point_deductions WHERE season_id = ? GROUP BY team_id) d ON d.team_id = t.id
+						    WHERE st.season_id = ?
+						    ORDER BY t.id`, r.seasonID, r.seasonID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var teams []Team
+	for rows.Next() {
+		var t Team
+		if err := rows.Scan(&t.ID, &t.Name, &t.Strength, &t.Points, &t.GoalsFor, &t.GoalsAgainst, &t.GoalDiff, &t.Wins, &t.Draws, &t.Losses, &t.FairPlayPoints, &t.PointsDeducted, &t.Seed, &t.Group); err != nil {
+			return nil, err
+		}
+		teams = append(teams, t)
+	}
+	return teams, rows.Err()
+}
+
+// Matches reads every match of the season ordered by week
+func (r *sqlRepository) Matches() ([]Match, error) {
+	rows, err := r.q.Query(`SELECT id, name_home, name_away, home_team_id, away_team_id, home_goals, away_goals, week, played, season_id,
+								   round, leg, tie, extra_time, home_penalties, away_penalties, postponed
+							FROM matches
+							WHERE season_id = ?
+							ORDER BY week, id`, r.seasonID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []Match
+	for rows.Next() {
+		m, err := scanMatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
+
+// Match reads a single match of the season by id
+func (r *sqlRepository) Match(id int) (Match, error) {
+	row := r.q.QueryRow(`SELECT id, name_home, name_away, home_team_id, away_team_id, home_goals, away_goals, week, played, season_id,
+							   round, leg, tie, extra_time, home_penalties, away_penalties, postponed
+						FROM matches
+						WHERE id = ? AND season_id = ?`, id, r.seasonID)
+	m, err := scanMatch(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Match{}, errMatchNotFound
+	}
+	return m, err
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanMatch reads one match row selected with the standard column order
+func scanMatch(row rowScanner) (Match, error) {
+	var m Match
+	var homeGoals, awayGoals, homePenalties, awayPenalties sql.NullInt64
+	if err := row.Scan(&m.ID, &m.NameHome, &m.NameAway, &m.HomeTeamID, &m.AwayTeamID, &homeGoals, &awayGoals, &m.Week, &m.Played, &m.SeasonID,
+		&m.Round, &m.Leg, &m.Tie, &m.ExtraTime, &homePenalties, &awayPenalties, &m.Postponed); err != nil {
+		return Match{}, err
+	}
+	m.HomeGoals = nullableInt(homeGoals)
+	m.AwayGoals = nullableInt(awayGoals)
+	m.HomePenalties = nullableInt(homePenalties)
+	m.AwayPenalties = nullableInt(awayPenalties)
+	return m, nil
+}
+
+// nullableInt converts a nullable column to a pointer, nil for NULL
+func nullableInt(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	val := int(v.Int64)
+	return &val
+}
+
+// SaveMatchResult stores the score of a match and marks it as played
+func (r *sqlRepository) SaveMatchResult(id, homeGoals, awayGoals int) error {
+	_, err := r.q.Exec("UPDATE matches SET home_goals = ?, away_goals = ?, played = true, postponed = false WHERE id = ? AND season_id = ?", homeGoals, awayGoals, id, r.seasonID)
+	return err
+}
+
+// SaveKnockoutResult stores the score of a cup match including extra time and penalties, and marks it as played
+func (r *sqlRepository) SaveKnockoutResult(m Match) error {
+	_, err := r.q.Exec(`UPDATE matches
+						SET home_goals = ?, away_goals = ?, extra_time = ?, home_penalties = ?, away_penalties = ?, played = true
+						WHERE id = ? AND season_id = ?`,
+		m.HomeGoals, m.AwayGoals, m.ExtraTime, m.HomePenalties, m.AwayPenalties, m.ID, r.seasonID)
+	return err
+}
+
+// ReplaceMatches deletes every match of the season and inserts the given ones, returning them with their new ids
+func (r *sqlRepository) ReplaceMatches(matches []Match) ([]Match, error) {
+	if _, err := r.q.Exec("DELETE FROM matches WHERE season_id = ?", r.seasonID); err != nil {
+		return nil, err
+	}
+	return r.AddMatches(matches)
+}
+
+// AddMatches inserts the given matches next to the existing ones, returning them with their new ids
+func (r *sqlRepository) AddMatches(matches []Match) ([]Match, error) {
+	inserted := make([]Match, len(matches))
+	for i, m := range matches {
+		m.SeasonID = r.seasonID
+		res, err := r.q.Exec(`INSERT INTO matches (name_home, name_away, home_team_id, away_team_id, home_goals, away_goals, week, played, season_id,
+												  round, leg, tie, extra_time, home_penalties, away_penalties, postponed)
+							  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			m.NameHome, m.NameAway, m.HomeTeamID, m.AwayTeamID, m.HomeGoals, m.AwayGoals, m.Week, m.Played, m.SeasonID,
+			m.Round, m.Leg, m.Tie, m.ExtraTime, m.HomePenalties, m.AwayPenalties, m.Postponed)
+		if err != nil {
+			return nil, err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		m.ID = int(id)
+		inserted[i] = m
+	}
+	return inserted, nil
+}
+
+// RescheduleMatch moves a match of the season to another week or marks it as postponed
+func (r *sqlRepository) RescheduleMatch(id, week int, postponed bool) error {
+	_, err := r.q.Exec("UPDATE matches SET week = ?, postponed = ? WHERE id = ? AND season_id = ?", week, postponed, id, r.seasonID)
+	return err
+}
+
+// DeleteMatch removes a match of the season
+func (r *sqlRepository) DeleteMatch(id int) error {
+	res, err := r.q.Exec("DELETE FROM matches WHERE id = ? AND season_id = ?", id, r.seasonID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errMatchNotFound
+	}
+	return nil
+}
+
+// SaveStandings overwrites the counters and fair play points stored for the season on the season_teams table
+func (r *sqlRepository) SaveStandings(teams []Team) error {
+	for _, t := range teams {
+		_, err := r.q.Exec(`UPDATE season_teams
+						SET points = ?,
+							goals_for = ?,
+							goals_against = ?,
+							goal_diff = ?,
+							wins = ?,
+							draws = ?,
+							losses = ?,
+							fair_play_points = ?
+						WHERE season_id = ? AND team_id = ?`,
+			t.Points, t.GoalsFor, t.GoalsAgainst, t.GoalDiff, t.Wins, t.Draws, t.Losses, t.FairPlayPoints, r.seasonID, t.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ratings reads the rating history of the season ordered by team and week
+func (r *sqlRepository) Ratings() ([]RatingPoint, error) {
+	rows, err := r.q.Query("SELECT team_id, week, rating FROM team_ratings WHERE season_id = ? ORDER BY team_id, week", r.seasonID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []RatingPoint{}
+	for rows.Next() {
+		var p RatingPoint
+		if err := rows.Scan(&p.TeamID, &p.Week, &p.Rating); err != nil {
+			return nil, err
+		}
+		history = append(history, p)
+	}
+	return history, rows.Err()
+}
+
+// SaveRatings replaces the rating history of the season
+func (r *sqlRepository) SaveRatings(history []RatingPoint) error {
+	if _, err := r.q.Exec("DELETE FROM team_ratings WHERE season_id = ?", r.seasonID); err != nil {
+		return err
+	}
+	for _, p := range history {
+		if _, err := r.q.Exec("INSERT INTO team_ratings (season_id, team_id, week, rating) VALUES (?, ?, ?, ?)", r.seasonID, p.TeamID, p.Week, p.Rating); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Deductions reads the points deductions ledger of the season ordered by date
+func (r *sqlRepository) Deductions() ([]Deduction, error) {
+	rows, err := r.q.Query(`SELECT id, season_id, team_id, points, reason, deducted_on
+						    FROM point_deductions
+						    WHERE season_id = ?
+						    ORDER BY deducted_on, id`, r.seasonID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deductions := []Deduction{}
+	for rows.Next() {
+		var d Deduction
+		if err := rows.Scan(&d.ID, &d.SeasonID, &d.TeamID, &d.Points, &d.Reason, &d.Date); err != nil {
+			return nil, err
+		}
+		deductions = append(deductions, d)
+	}
+	return deductions, rows.Err()
+}
+
+// AddDeduction inserts a points deduction, returning it with its new id
+func (r *sqlRepository) AddDeduction(d Deduction) (Deduction, error) {
+	d.SeasonID = r.seasonID
+	res, err := r.q.Exec("INSERT INTO point_deductions (season_id, team_id, points, reason, deducted_on) VALUES (?, ?, ?, ?, ?)",
+		d.SeasonID, d.TeamID, d.Points, d.Reason, d.Date)
+	if err != nil {
+		return Deduction{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Deduction{}, err
+	}
+	d.ID = int(id)
+	return d, nil
+}
+
+// DeleteDeduction removes a points deduction
+func (r *sqlRepository) DeleteDeduction(id int) error {
+	res, err := r.q.Exec("DELETE FROM point_deductions WHERE id = ? AND season_id = ?", id, r.seasonID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errDeductionNotFound
+	}
+	return nil
+}
+
+// Atomic runs fn in a transaction that holds the season lock, as long as the season is not archived
+func (r *sqlRepository) Atomic(fn func(repo LeagueRepository) error) error {
+	run := func(repo *sqlRepository) error {
+		status, err := lockSeason(repo.q, repo.dialect, repo.seasonID)
+		if err != nil {
+			return err
+		}
+		if status == seasonArchived {
+			return errSeasonArchived
+		}
+		return fn(repo)
+	}
+	if r.db == nil {
+		// Already inside a transaction
+		return run(r)
+	}
+	return withTx(r.db, func(tx *sql.Tx) error {
+		return run(&sqlRepository{q: tx, dialect: r.dialect, seasonID: r.seasonID})
+	})
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// sqlStore implements LeagueStore on top of the leagues, seasons and teams tables in MySQL or SQLite
+type sqlStore struct {
+	db      *sql.DB // nil while running inside Atomic
+	q       queryer
+	dialect string
+}
+
+// newSQLStore creates a store using the given connection pool and SQL dialect
+func newSQLStore(db *sql.DB, dialect string) *sqlStore {
+	return &sqlStore{db: db, q: db, dialect: dialect}
+}
+
+// Leagues reads every league ordered by id
+func (s *sqlStore) Leagues() ([]League, error) {
+	rows, err := s.q.Query("SELECT id, name, division_below_id, promotion_places, playoff_places, format, legs, group_count, group_qualifiers, best_placed, engine FROM leagues ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	leagues := []League{}
+	for rows.Next() {
+		l, err := scanLeague(rows)
+		if err != nil {
+			return nil, err
+		}
+		leagues = append(leagues, l)
+	}
+	return leagues, rows.Err()
+}
+
+// League reads a single league by id
+func (s *sqlStore) League(id int) (League, error) {
+	row := s.q.QueryRow("SELECT id, name, division_below_id, promotion_places, playoff_places, format, legs, group_count, group_qualifiers, best_placed, engine FROM leagues WHERE id = ?", id)
+	l, err := scanLeague(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return League{}, errLeagueNotFound
+	}
+	return l, err
+}
+
+// LockLeagues takes row locks on the leagues for the rest of the transaction
+func (s *sqlStore) LockLeagues(ids ...int) error {
+	return lockLeagues(s.q, s.dialect, ids)
+}
+
+// scanLeague reads one league row selected with the standard column order
+func scanLeague(row rowScanner) (League, error) {
+	var l League
+	var below sql.NullInt64
+	if err := row.Scan(&l.ID, &l.Name, &below, &l.PromotionPlaces, &l.PlayoffPlaces, &l.Format, &l.Legs, &l.Groups, &l.GroupQualifiers, &l.BestPlaced, &l.Engine); err != nil {
+		return League{}, err
+	}
+	if below.Valid {
+		id := int(below.Int64)
+		l.DivisionBelowID = &id
+	}
+	return l, nil
+}
+
+// CreateLeague inserts a league, returning it with its new id
+func (s *sqlStore) CreateLeague(l League) (League, error) {
+	res, err := s.q.Exec("INSERT INTO leagues (name, division_below_id, promotion_places, playoff_places, format, legs, group_count, group_qualifiers, best_placed, engine) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		l.Name, l.DivisionBelowID, l.PromotionPlaces, l.PlayoffPlaces, l.Format, l.Legs, l.Groups, l.GroupQualifiers, l.BestPlaced, l.Engine)
+	if err != nil {
+		return League{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return League{}, err
+	}
+	l.ID = int(id)
+	return l, nil
+}
+
+// SaveLeague overwrites the name, the match engine and the division settings of a league, its format is fixed at creation
+func (s *sqlStore) SaveLeague(l League) error {
+	_, err := s.q.Exec("UPDATE leagues SET name = ?, division_below_id = ?, promotion_places = ?, playoff_places = ?, engine = ? WHERE id = ?",
+		l.Name, l.DivisionBelowID, l.PromotionPlaces, l.PlayoffPlaces, l.Engine, l.ID)
+	return err
+}
+
+// Seasons reads every season of a league, oldest first
+func (s *sqlStore) Seasons(leagueID int) ([]Season, error) {
+	rows, err := s.q.Query("SELECT id, league_id, name, status FROM seasons WHERE league_id = ? ORDER BY id", leagueID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seasons := []Season{}
+	for rows.Next() {
+		var season Season
+		if err := rows.Scan(&season.ID, &season.LeagueID, &season.Name, &season.Status); err != nil {
+			return nil, err
+		}
+		seasons = append(seasons, season)
+	}
+	return seasons, rows.Err()
+}
+
+// Season reads a single season by id
+func (s *sqlStore) Season(id int) (Season, error) {
+	var season Season
+	err := s.q.QueryRow("SELECT id, league_id, name, status FROM seasons WHERE id = ?", id).
+		Scan(&season.ID, &season.LeagueID, &season.Name, &season.Status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Season{}, errSeasonNotFound
+	}
+	return season, err
+}
+
+// CreateSeason inserts an active season, returning it with its new id
+func (s *sqlStore) CreateSeason(leagueID int, name string) (Season, error) {
+	res, err := s.q.Exec("INSERT INTO seasons (league_id, name, status) VALUES (?, ?, ?)", leagueID, name, seasonActive)
+	if err != nil {
+		return Season{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Season{}, err
+	}
+	return Season{ID: int(id), LeagueID: leagueID, Name: name, Status: seasonActive}, nil
+}
+
+// ArchiveSeasons archives every active season of a league
+func (s *sqlStore) ArchiveSeasons(leagueID int) error {
+	_, err := s.q.Exec("UPDATE seasons SET status = ? WHERE league_id = ? AND status = ?", seasonArchived, leagueID, seasonActive)
+	return err
+}
+
+// ArchiveSeason archives a single season
+func (s *sqlStore) ArchiveSeason(id int) error {
+	_, err := s.q.Exec("UPDATE seasons SET status = ? WHERE id = ?", seasonArchived, id)
+	return err
+}
+
+// AddSeasonTeams enters teams into a season with empty counters, keeping their seed and group
+func (s *sqlStore) AddSeasonTeams(seasonID int, teams []Team) error {
+	for _, t := range teams {
+		if _, err := s.q.Exec("INSERT INTO season_teams (season_id, team_id, seed, group_no) VALUES (?, ?, ?, ?)", seasonID, t.ID, t.Seed, t.Group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AllTeams reads every team ordered by id
+func (s *sqlStore) AllTeams() ([]Team, error) {
+	rows, err := s.q.Query("SELECT id, name, strength FROM teams ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	teams := []Team{}
+	for rows.Next() {
+		var t Team
+		if err := rows.Scan(&t.ID, &t.Name, &t.Strength); err != nil {
+			return nil, err
+		}
+		teams = append(teams, t)
+	}
+	return teams, rows.Err()
+}
+
+// CreateTeam inserts a team, returning it with its new id
+func (s *sqlStore) CreateTeam(name string, strength int) (Team, error) {
+	res, err := s.q.Exec("INSERT INTO teams (name, strength) VALUES (?, ?)", name, strength)
+	if err != nil {
+		return Team{}, asNameTaken(err, name)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Team{}, err
+	}
+	return Team{ID: int(id), Name: name, Strength: strength}, nil
+}
+
+// SaveTeam overwrites the name and strength of a team and renames it on its matches
+func (s *sqlStore) SaveTeam(t Team) error {
+	if _, err := s.q.Exec("UPDATE teams SET name = ?, strength = ? WHERE id = ?", t.Name, t.Strength, t.ID); err != nil {
+		return asNameTaken(err, t.Name)
+	}
+	if _, err := s.q.Exec("UPDATE matches SET name_home = ? WHERE home_team_id = ?", t.Name, t.ID); err != nil {
+		return err
+	}
+	_, err := s.q.Exec("UPDATE matches SET name_away = ? WHERE away_team_id = ?", t.Name, t.ID)
+	return err
+}
+
+// HasPlayedMatches reports whether a team has played a match in any season
+func (s *sqlStore) HasPlayedMatches(teamID int) (bool, error) {
+	var count int
+	err := s.q.QueryRow("SELECT COUNT(*) FROM matches WHERE played = true AND (home_team_id = ? OR away_team_id = ?)", teamID, teamID).Scan(&count)
+	return count > 0, err
+}
+
+// DeleteTeam removes a team with its season entries, fixtures, deductions, ratings and fitted parameters
+func (s *sqlStore) DeleteTeam(id int) error {
+	if _, err := s.q.Exec("DELETE FROM point_deductions WHERE team_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := s.q.Exec("DELETE FROM team_ratings WHERE team_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := s.q.Exec("DELETE FROM fitted_teams WHERE team_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := s.q.Exec("DELETE FROM matches WHERE home_team_id = ? OR away_team_id = ?", id, id); err != nil {
+		return err
+	}
+	if _, err := s.q.Exec("DELETE FROM season_teams WHERE team_id = ?", id); err != nil {
+		return err
+	}
+	res, err := s.q.Exec("DELETE FROM teams WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errTeamNotFound
+	}
+	return nil
+}
+
+// FittedModel reads the model of the fitted engine, a model that was never fitted has no teams
+func (s *sqlStore) FittedModel() (FittedModel, error) {
+	m := FittedModel{Teams: make(map[int]FittedParameters)}
+	err := s.q.QueryRow("SELECT home_advantage, base_goals FROM fitted_model WHERE id = 1").Scan(&m.HomeAdvantage, &m.BaseGoals)
+	if errors.Is(err, sql.ErrNoRows) {
+		return m, nil
+	}
+	if err != nil {
+		return FittedModel{}, err
+	}
+
+	rows, err := s.q.Query("SELECT team_id, attack, defence FROM fitted_teams")
+	if err != nil {
+		return FittedModel{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var p FittedParameters
+		if err := rows.Scan(&id, &p.Attack, &p.Defence); err != nil {
+			return FittedModel{}, err
+		}
+		m.Teams[id] = p
+	}
+	return m, rows.Err()
+}
+
+// SaveFittedModel replaces the model of the fitted engine
+func (s *sqlStore) SaveFittedModel(m FittedModel) error {
+	if _, err := s.q.Exec("DELETE FROM fitted_teams"); err != nil {
+		return err
+	}
+	if _, err := s.q.Exec("DELETE FROM fitted_model"); err != nil {
+		return err
+	}
+	if _, err := s.q.Exec("INSERT INTO fitted_model (id, home_advantage, base_goals) VALUES (1, ?, ?)", m.HomeAdvantage, m.BaseGoals); err != nil {
+		return err
+	}
+	for id, p := range m.Teams {
+		if _, err := s.q.Exec("INSERT INTO fitted_teams (team_id, attack, defence) VALUES (?, ?, ?)", id, p.Attack, p.Defence); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ForSeason returns the repository of one season sharing the store's connection or transaction
+func (s *sqlStore) ForSeason(seasonID int) LeagueRepository {
+	return &sqlRepository{db: s.db, q: s.q, dialect: s.dialect, seasonID: seasonID}
+}
+
+// Atomic runs fn in a transaction
+func (s *sqlStore) Atomic(fn func(store LeagueStore) error) error {
+	if s.db == nil {
+		// Already inside a transaction
+		return fn(s)
+	}
+	return withTx(s.db, func(tx *sql.Tx) error {
+		return fn(&sqlStore{q: tx, dialect: s.dialect})
+	})
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// sqliteTestStore opens a migrated SQLite database in a temporary directory
+func sqliteTestStore(t *testing.T) *sqlStore {
+	t.Helper()
+	db, err := sql.Open("sqlite", sqliteDSN(filepath.Join(t.TempDir(), "league.db")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := migrate(db, "sqlite"); err != nil {
+		t.Fatal(err)
+	}
+	return newSQLStore(db, "sqlite")
+}
+
+func TestUniqueIndexRefusesDuplicateTeamNames(t *testing.T) {
+	store := sqliteTestStore(t)
+
+	// Writing straight to the store skips checkTeamName, as a concurrent request that passed it would
+	if _, err := store.CreateTeam("Brentford", 50); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateTeam("BRENTFORD", 50); !errors.Is(err, errTeamNameTaken) {
+		t.Errorf("creating a duplicate name gave %v, want %v", err, errTeamNameTaken)
+	}
+
+	teams, err := store.AllTeams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	renamed := teams[0]
+	renamed.Name = "brentford"
+	if err := store.SaveTeam(renamed); !errors.Is(err, errTeamNameTaken) {
+		t.Errorf("renaming %s to a taken name gave %v, want %v", teams[0].Name, err, errTeamNameTaken)
+	}
+}
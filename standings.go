@@ -0,0 +1,222 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StandingsDrift describes one counter on the teams table that disagrees with the match results
+type StandingsDrift struct {
+	TeamID   int    `json:"team_id"`
+	Name     string `json:"name"`
+	Field    string `json:"field"`
+	Stored   int    `json:"stored"`
+	Computed int    `json:"computed"`
+}
+
+// computeStandings builds the league table purely from the played matches.
+// Only ID, Name, Strength, FairPlayPoints and PointsDeducted are taken from the given teams, every counter is recomputed
+// and points start from the deducted total. With ratings enabled the Rating the engines play with is replayed as well.
+func computeStandings(teams []Team, matches []Match, settings LeagueSettings) []Team {
+	table := make([]Team, len(teams))
+	index := make(map[int]int, len(teams))
+	played := make([]int, len(teams)) // round-robin matches played by each team
+	for i, t := range teams {
+		table[i] = Team{
+			ID:             t.ID,
+			Name:           t.Name,
+			Strength:       t.Strength,
+			FairPlayPoints: t.FairPlayPoints,
+			PointsDeducted: t.PointsDeducted,
+			Points:         -t.PointsDeducted,
+		}
+		index[t.ID] = i
+	}
+
+	for _, m := range matches {
+		if !m.Played || m.HomeGoals == nil || m.AwayGoals == nil {
+			continue
+		}
+		hi, homeOK := index[m.HomeTeamID]
+		ai, awayOK := index[m.AwayTeamID]
+		if !homeOK || !awayOK {
+			continue
+		}
+		applyResult(&table[hi], &table[ai], *m.HomeGoals, *m.AwayGoals, settings.Points)
+		if m.Round == 0 {
+			played[hi]++
+			played[ai]++
+		}
+	}
+
+	if settings.Ratings.enabled() {
+		// The table cannot fail, a broken replay is logged and every team keeps the rating of its strength
+		ratings, _, err := replayRatings(teams, matches, settings.Ratings)
+		if err != nil {
+			log.Printf("ratings replay failed: %v", err)
+		}
+		for i := range table {
+			if rating, ok := ratings[table[i].ID]; ok {
+				table[i].Rating = rating
+			} else {
+				table[i].Rating = roundRating(ratingFromStrength(table[i].Strength))
+			}
+		}
+	}
+
+	// Knockout matches are not counted, a team knocked out has no games in hand
+	mostPlayed := slices.Max(append(played, 0))
+	for i := range table {
+		table[i].GamesInHand = mostPlayed - played[i]
+	}
+
+	settings.rankTable(table, playedResults(matches), nil)
+	return table
+}
+
+// applyResult adds one played match to both teams' rows
+func applyResult(home, away *Team, homeGoals, awayGoals int, points PointsSystem) {
+	addResult(home, homeGoals, awayGoals, points)
+	addResult(away, awayGoals, homeGoals, points)
+}
+
+// addResult updates a single team's counters from its own point of view
+func addResult(team *Team, goalsFor, goalsAgainst int, points PointsSystem) {
+	team.GoalsFor += goalsFor
+	team.GoalsAgainst += goalsAgainst
+	team.GoalDiff = team.GoalsFor - team.GoalsAgainst
+
+	if goalsFor > goalsAgainst {
+		team.Wins++
+	} else if goalsFor < goalsAgainst {
+		team.Losses++
+	} else {
+		team.Draws++
+	}
+	team.Points += points.pointsFor(goalsFor, goalsAgainst)
+}
+
+// compareStandings lists every counter where the stored teams differ from the computed table
+func compareStandings(stored, computed []Team) []StandingsDrift {
+	byID := make(map[int]Team, len(computed))
+	for _, t := range computed {
+		byID[t.ID] = t
+	}
+
+	drift := []StandingsDrift{}
+	for _, s := range stored {
+		c := byID[s.ID]
+		fields := []struct {
+			name             string
+			stored, computed int
+		}{
+			{"points", s.Points, c.Points},
+			{"goals_for", s.GoalsFor, c.GoalsFor},
+			{"goals_against", s.GoalsAgainst, c.GoalsAgainst},
+			{"goal_diff", s.GoalDiff, c.GoalDiff},
+			{"wins", s.Wins, c.Wins},
+			{"draws", s.Draws, c.Draws},
+			{"losses", s.Losses, c.Losses},
+		}
+		for _, f := range fields {
+			if f.stored != f.computed {
+				drift = append(drift, StandingsDrift{
+					TeamID:   s.ID,
+					Name:     s.Name,
+					Field:    f.name,
+					Stored:   f.stored,
+					Computed: f.computed,
+				})
+			}
+		}
+	}
+	return drift
+}
+
+// syncStandings recomputes the table inside an atomic block and overwrites the stored counters and rating history with it
+func syncStandings(repo LeagueRepository, settings LeagueSettings) ([]Team, error) {
+	stored, err := repo.Teams()
+	if err != nil {
+		return nil, err
+	}
+	matches, err := repo.Matches()
+	if err != nil {
+		return nil, err
+	}
+
+	teams := computeStandings(stored, matches, settings)
+	if err := repo.SaveStandings(teams); err != nil {
+		return nil, err
+	}
+
+	// The rating history is replayed from the same matches, so an edited result rewrites every week after it
+	var history []RatingPoint
+	if settings.Ratings.enabled() {
+		if _, history, err = replayRatings(stored, matches, settings.Ratings); err != nil {
+			return nil, err
+		}
+	}
+	if err := repo.SaveRatings(history); err != nil {
+		return nil, err
+	}
+	return teams, nil
+}
+
+// RebuildStandingsHandler handles the request to overwrite the stored counters with the recomputed table
+func RebuildStandingsHandler(teamService TeamService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		teams, err := teamService.RebuildStandings()
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message":   "Standings rebuilt successfully",
+			"standings": teams,
+		})
+	}
+}
+
+// CheckStandingsHandler handles the request to report drift between stored counters and match results
+func CheckStandingsHandler(teamService TeamService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		drift, err := teamService.CheckStandings()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"consistent": len(drift) == 0,
+			"drift":      drift,
+		})
+	}
+}
+
+// FairPlayHandler handles the request to set the fair play points of a team
+func FairPlayHandler(teamService TeamService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		type FairPlayRequest struct {
+			TeamID int  `json:"team_id"`
+			Points *int `json:"points"`
+		}
+		var req FairPlayRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+		if req.Points == nil || *req.Points < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "points must be zero or more fair play points"})
+			return
+		}
+
+		teams, err := teamService.SetFairPlayPoints(req.TeamID, *req.Points)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"standings": teams})
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Bounds of a team's strength and name, the match engines only compare strengths so the scale is free
+const (
+	minStrength       = 1
+	maxStrength       = 100
+	maxTeamNameLength = 50
+)
+
+// TeamAdminService interface defines methods for managing the teams shared by every league
+type TeamAdminService interface {
+	CreateTeam(name string, strength int) (Team, error)
+	UpdateTeam(id int, name *string, strength *int) (Team, error)
+	DeleteTeam(id int) error
+}
+
+// MyTeamAdminService implements TeamAdminService interface
+type MyTeamAdminService struct {
+	store LeagueStore
+}
+
+// CreateTeam creates a team, it joins a season when the season is started with it
+func (s *MyTeamAdminService) CreateTeam(name string, strength int) (Team, error) {
+	var team Team
+	err := s.store.Atomic(func(store LeagueStore) error {
+		var err error
+		team, err = createTeam(store, name, strength)
+		return err
+	})
+	return team, err
+}
+
+// UpdateTeam renames a team and/or changes its strength, a nil field keeps its value.
+// The new strength counts from the next simulated match on, results already played are kept.
+func (s *MyTeamAdminService) UpdateTeam(id int, name *string, strength *int) (Team, error) {
+	var team Team
+	err := s.store.Atomic(func(store LeagueStore) error {
+		teams, err := store.AllTeams()
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, t := range teams {
+			if t.ID == id {
+				team, found = t, true
+			}
+		}
+		if !found {
+			return fmt.Errorf("%w: %d", errTeamNotFound, id)
+		}
+		if name != nil {
+			if err := checkTeamName(teams, *name, id); err != nil {
+				return err
+			}
+			team.Name = *name
+		}
+		if strength != nil {
+			team.Strength = *strength
+		}
+		return store.SaveTeam(team)
+	})
+	if err != nil {
+		return Team{}, err
+	}
+	return team, nil
+}
+
+// DeleteTeam deletes a team that has not played yet, withdrawing it from its seasons with its fixtures and deductions
+func (s *MyTeamAdminService) DeleteTeam(id int) error {
+	return s.store.Atomic(func(store LeagueStore) error {
+		played, err := store.HasPlayedMatches(id)
+		if err != nil {
+			return err
+		}
+		if played {
+			return errTeamHasResults
+		}
+
+		// Brackets are drawn from the entry list, withdrawing a team would shift every tie after it
+		leagues, err := store.Leagues()
+		if err != nil {
+			return err
+		}
+		for _, l := range leagues {
+			if l.Format == formatLeague {
+				continue
+			}
+			seasons, err := store.Seasons(l.ID)
+			if err != nil {
+				return err
+			}
+			for _, season := range seasons {
+				if season.Status != seasonActive {
+					continue
+				}
+				teams, err := store.ForSeason(season.ID).Teams()
+				if err != nil {
+					return err
+				}
+				if slices.ContainsFunc(teams, func(t Team) bool { return t.ID == id }) {
+					return fmt.Errorf("%w: team %d is drawn in the running %s %s", errInvalidInput, id, l.Format, l.Name)
+				}
+			}
+		}
+		return store.DeleteTeam(id)
+	})
+}
+
+// createTeam creates a team after checking that its name is free
+func createTeam(store LeagueStore, name string, strength int) (Team, error) {
+	teams, err := store.AllTeams()
+	if err != nil {
+		return Team{}, err
+	}
+	if err := checkTeamName(teams, name, 0); err != nil {
+		return Team{}, err
+	}
+	return store.CreateTeam(name, strength)
+}
+
+// checkTeamName refuses a name already used by another team than the one with the given id, ignoring case.
+// Writers racing past the check are stopped by the unique index on the name in the SQL stores.
+func checkTeamName(teams []Team, name string, id int) error {
+	for _, t := range teams {
+		if t.ID != id && strings.EqualFold(t.Name, name) {
+			return fmt.Errorf("%w: %s", errTeamNameTaken, t.Name)
+		}
+	}
+	return nil
+}
+
+// validateTeamName checks a trimmed team name
+func validateTeamName(name string) error {
+	if name == "" || utf8.RuneCountInString(name) > maxTeamNameLength {
+		return fmt.Errorf("name must be between 1 and %d characters", maxTeamNameLength)
+	}
+	return nil
+}
+
+// validateStrength checks that a strength lies in the allowed range
+func validateStrength(strength int) error {
+	if strength < minStrength || strength > maxStrength {
+		return fmt.Errorf("strength must be between %d and %d", minStrength, maxStrength)
+	}
+	return nil
+}
+
+// CreateTeamHandler handles the request to create a team
+func CreateTeamHandler(teamAdminService TeamAdminService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		type CreateTeamRequest struct {
+			Name     string `json:"name"`
+			Strength int    `json:"strength"`
+		}
+		var req CreateTeamRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+		req.Name = strings.TrimSpace(req.Name)
+		if err := validateTeamName(req.Name); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := validateStrength(req.Strength); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		team, err := teamAdminService.CreateTeam(req.Name, req.Strength)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"team": team})
+	}
+}
+
+// UpdateTeamHandler handles the request to change a team. PUT replaces both the name and the strength,
+// PATCH only the fields that are sent.
+func UpdateTeamHandler(teamAdminService TeamAdminService, partial bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := pathID(c, "id")
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		type UpdateTeamRequest struct {
+			Name     *string `json:"name"`
+			Strength *int    `json:"strength"`
+		}
+		var req UpdateTeamRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+		if !partial && (req.Name == nil || req.Strength == nil) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name and strength are required, use PATCH to change only one of them"})
+			return
+		}
+		if req.Name == nil && req.Strength == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "send a name and/or a strength"})
+			return
+		}
+		if req.Name != nil {
+			name := strings.TrimSpace(*req.Name)
+			if err := validateTeamName(name); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			req.Name = &name
+		}
+		if req.Strength != nil {
+			if err := validateStrength(*req.Strength); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		team, err := teamAdminService.UpdateTeam(id, req.Name, req.Strength)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"team": team})
+	}
+}
+
+// DeleteTeamHandler handles the request to delete a team that has not played any match
+func DeleteTeamHandler(teamAdminService TeamAdminService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := pathID(c, "id")
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		if err := teamAdminService.DeleteTeam(id); err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Team deleted successfully"})
+	}
+}
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GroupTable is the table of one group of a tournament, ranked with the group tiebreakers
+type GroupTable struct {
+	Group     string `json:"group"`
+	Standings []Team `json:"standings"`
+}
+
+// TournamentState is a tournament season: the group tables and, once every group match is played,
+// the qualified teams with their knockout bracket
+type TournamentState struct {
+	Groups             []GroupTable `json:"groups"`
+	GroupStageComplete bool         `json:"group_stage_complete"`
+	Qualified          []CupEntrant `json:"qualified"` // seeded by group place and record, group-mates kept apart in the first round
+	Bracket            *CupBracket  `json:"bracket"`
+}
+
+// TournamentOdds is the chance of a team to finish in every place of its group, to qualify and to reach every knockout stage, in percent
+type TournamentOdds struct {
+	TeamID   int       `json:"team_id"`
+	Name     string    `json:"name"`
+	Group    string    `json:"group"`
+	Position []float64 `json:"position"` // index 0 is first place in the group
+	Qualify  float64   `json:"qualify"`
+	Reach    []float64 `json:"reach"` // aligned with TournamentProbabilities.Stages
+}
+
+// TournamentProbabilities lists the knockout stages and every team's odds of getting through the tournament
+type TournamentProbabilities struct {
+	Stages      []string         `json:"stages"`
+	Teams       []TournamentOdds `json:"teams"`
+	Simulations int              `json:"simulations"`
+}
+
+// TournamentService interface defines methods for playing a season in the tournament format
+type TournamentService interface {
+	State() (TournamentState, error)
+	PlayRound(rng *rand.Rand) (string, TournamentState, error)
+	Probabilities(rng *rand.Rand, simulations int) (TournamentProbabilities, error)
+}
+
+// MyTournamentService implements TournamentService interface
+type MyTournamentService struct {
+	repo     LeagueRepository
+	settings LeagueSettings
+	league   League
+}
+
+// State returns the group tables and the knockout bracket with every result played so far
+func (s *MyTournamentService) State() (TournamentState, error) {
+	teams, err := s.repo.Teams()
+	if err != nil {
+		return TournamentState{}, err
+	}
+	matches, err := s.repo.Matches()
+	if err != nil {
+		return TournamentState{}, err
+	}
+	state, _ := s.tournamentState(teams, matches)
+	return state, nil
+}
+
+// PlayRound plays the next week of the group stage, or the next knockout round once the groups are complete.
+// The knockout ties are drawn as soon as their teams are known. It returns the name of the stage played and the updated state.
+func (s *MyTournamentService) PlayRound(rng *rand.Rand) (string, TournamentState, error) {
+	var stage string
+	var state TournamentState
+	err := s.repo.Atomic(func(repo LeagueRepository) error {
+		teams, err := repo.Teams()
+		if err != nil {
+			return err
+		}
+		matches, err := repo.Matches()
+		if err != nil {
+			return err
+		}
+		byID := teamsByID(teams)
+
+		current, _ := s.tournamentState(teams, matches)
+		if !current.GroupStageComplete {
+			week := 0
+			for _, m := range matches {
+				if m.Round == 0 && !m.Played && (week == 0 || m.Week < week) {
+					week = m.Week
+				}
+			}
+			for _, m := range matches {
+				if m.Round == 0 && !m.Played && m.Week == week {
+					homeGoals, awayGoals := s.settings.Engine.SimulateMatch(rng, byID[m.HomeTeamID], byID[m.AwayTeamID])
+					if err := repo.SaveMatchResult(m.ID, homeGoals, awayGoals); err != nil {
+						return err
+					}
+				}
+			}
+			stage = fmt.Sprintf("Group stage week %d", week)
+		} else {
+			round, played := playCupRound(*current.Bracket, byID, s.settings.Engine, rng)
+			if round == 0 {
+				return fmt.Errorf("%w: the tournament has been decided", errInvalidInput)
+			}
+			for _, m := range played {
+				if err := repo.SaveKnockoutResult(m); err != nil {
+					return err
+				}
+			}
+			stage = current.Bracket.Rounds[round-1].Name
+		}
+
+		matches, err = repo.Matches()
+		if err != nil {
+			return err
+		}
+		state, _ = s.tournamentState(teams, matches)
+		if state.Bracket != nil {
+			next, err := repo.AddMatches(pendingCupMatches(*state.Bracket, s.league.Legs, knockoutWeek(matches)))
+			if err != nil {
+				return err
+			}
+			state, _ = s.tournamentState(teams, append(matches, next...))
+		}
+
+		// Keep the stored counters in line with the results, like every other write
+		_, err = syncStandings(repo, s.settings)
+		return err
+	})
+	if err != nil {
+		return "", TournamentState{}, err
+	}
+	return stage, state, nil
+}
+
+// Probabilities plays the rest of the tournament many times and counts how often each team finishes in every group place,
+// qualifies and reaches each knockout stage
+func (s *MyTournamentService) Probabilities(rng *rand.Rand, simulations int) (TournamentProbabilities, error) {
+	teams, err := s.repo.Teams()
+	if err != nil {
+		return TournamentProbabilities{}, err
+	}
+	matches, err := s.repo.Matches()
+	if err != nil {
+		return TournamentProbabilities{}, err
+	}
+	if simulations <= 0 {
+		simulations = s.settings.Simulations
+	}
+
+	rounds := 0
+	for size := 1; size < s.league.Groups*s.league.GroupQualifiers+s.league.BestPlaced; size *= 2 {
+		rounds++
+	}
+	byID := teamsByID(teams)
+	positions := make(map[int][]int, len(teams))
+	qualified := make(map[int]int, len(teams))
+	counts := make(map[int][]int, len(teams))
+	for _, t := range teams {
+		positions[t.ID] = make([]int, len(teams))
+		counts[t.ID] = make([]int, rounds+1)
+	}
+
+	for i := 0; i < simulations; i++ {
+		simulated := cloneMatches(matches)
+		for j, m := range simulated {
+			if m.Round == 0 && !m.Played {
+				homeGoals, awayGoals := s.settings.Engine.SimulateMatch(rng, byID[m.HomeTeamID], byID[m.AwayTeamID])
+				simulated[j].HomeGoals, simulated[j].AwayGoals, simulated[j].Played = &homeGoals, &awayGoals, true
+			}
+		}
+
+		state, entrants := s.tournamentState(teams, simulated)
+		for _, group := range state.Groups {
+			for place, t := range group.Standings {
+				positions[t.ID][place]++
+			}
+		}
+		for _, t := range entrants {
+			qualified[t.ID]++
+		}
+		countCupStages(playOutCup(entrants, byID, simulated, s.league.Legs, knockoutWeek(simulated), s.settings.Engine, rng), counts)
+	}
+
+	result := TournamentProbabilities{Simulations: simulations}
+	for r := 1; r <= rounds; r++ {
+		result.Stages = append(result.Stages, cupRoundName(r, rounds))
+	}
+	result.Stages = append(result.Stages, "Winner")
+	for g, group := range teamsByGroup(teams) {
+		for _, t := range group {
+			odds := TournamentOdds{
+				TeamID:   t.ID,
+				Name:     t.Name,
+				Group:    groupName(g + 1),
+				Position: make([]float64, len(group)),
+				Qualify:  percentage(qualified[t.ID], simulations),
+				Reach:    make([]float64, rounds+1),
+			}
+			for place := range odds.Position {
+				odds.Position[place] = percentage(positions[t.ID][place], simulations)
+			}
+			for stage, count := range counts[t.ID] {
+				odds.Reach[stage] = percentage(count, simulations)
+			}
+			result.Teams = append(result.Teams, odds)
+		}
+	}
+	slices.SortStableFunc(result.Teams, func(a, b TournamentOdds) int {
+		return cmp.Compare(b.Reach[rounds], a.Reach[rounds])
+	})
+	return result, nil
+}
+
+// tournamentState ranks every group and, once the group stage is complete, seeds the qualified teams into the knockout bracket.
+// It also returns the qualified teams with their knockout seeds.
+func (s *MyTournamentService) tournamentState(teams []Team, matches []Match) (TournamentState, []Team) {
+	groupSettings := s.settings
+	groupSettings.Tiebreakers = s.settings.GroupTiebreakers
+
+	var groupMatches []Match
+	for _, m := range matches {
+		if m.Round == 0 {
+			groupMatches = append(groupMatches, m)
+		}
+	}
+	state := TournamentState{
+		Groups:             []GroupTable{},
+		GroupStageComplete: !slices.ContainsFunc(groupMatches, func(m Match) bool { return !m.Played }),
+		Qualified:          []CupEntrant{},
+	}
+	var tables [][]Team
+	for g, group := range teamsByGroup(teams) {
+		table := computeStandings(group, groupMatches, groupSettings)
+		tables = append(tables, table)
+		state.Groups = append(state.Groups, GroupTable{Group: groupName(g + 1), Standings: table})
+	}
+	if !state.GroupStageComplete {
+		return state, nil
+	}
+
+	qualified := s.qualifiers(tables)
+	bracket := buildCupBracket(qualified, matches, s.league.Legs)
+	state.Qualified = cupEntrants(qualified)
+	state.Bracket = &bracket
+	return state, qualified
+}
+
+// qualifiers picks the top teams of every group, then the best of the teams placed just below them.
+// They are seeded by their place and then by their record against the teams in the same place of the other groups,
+// and the seeds are then adjusted so that no team meets a team of its own group in the first knockout round.
+func (s *MyTournamentService) qualifiers(tables [][]Team) []Team {
+	// The groups never meet, so teams of different groups are compared without the head-to-head rules
+	crossGroup := s.settings
+	crossGroup.Tiebreakers = slices.DeleteFunc(slices.Clone(s.settings.GroupTiebreakers), Tiebreaker.headToHead)
+
+	var qualified []Team
+	for place := 0; place <= s.league.GroupQualifiers; place++ {
+		var level []Team
+		for g, table := range tables {
+			if place < len(table) {
+				t := table[place]
+				t.Group = g + 1
+				level = append(level, t)
+			}
+		}
+		crossGroup.rankTable(level, nil, nil)
+		if place == s.league.GroupQualifiers {
+			level = level[:min(s.league.BestPlaced, len(level))]
+		}
+		qualified = append(qualified, level...)
+	}
+	return keepGroupsApart(qualified)
+}
+
+// maxSeedingSearch bounds the search for a first round without group-mates, beyond it the seeds are left in order
+const maxSeedingSearch = 100000
+
+// keepGroupsApart seeds the qualifiers, best first, so that no first round tie of the bracket pairs two teams of the same group.
+// Seed s meets seed size+1-s, so the seeds are filled in order with the best remaining team that does not meet a group-mate:
+// the top seeds keep their teams whenever possible and group winners face runners-up of the other groups.
+// When no such draw exists the seeds are left in order.
+func keepGroupsApart(qualified []Team) []Team {
+	n := len(qualified)
+	size := 1
+	for size < n {
+		size *= 2
+	}
+	seeded := make([]Team, n)
+	used := make([]bool, n)
+	nodes := 0
+	var place func(seed int) bool
+	place = func(seed int) bool {
+		if seed > n {
+			return true
+		}
+		for i, t := range qualified {
+			if used[i] {
+				continue
+			}
+			if nodes++; nodes > maxSeedingSearch {
+				return false
+			}
+			// The opponent has the better seed, so it is already placed
+			if opponent := size + 1 - seed; opponent < seed && seeded[opponent-1].Group == t.Group {
+				continue
+			}
+			used[i], seeded[seed-1] = true, t
+			if place(seed + 1) {
+				return true
+			}
+			used[i] = false
+		}
+		return false
+	}
+	if !place(1) {
+		seeded = slices.Clone(qualified)
+	}
+	for i := range seeded {
+		seeded[i].Seed = i + 1
+	}
+	return seeded
+}
+
+// drawGroups puts the teams into pots of one team per group by strength and draws one team of every pot into each group,
+// so that the strongest teams are kept apart. The drawn teams are seeded in pot order.
+func drawGroups(teams []Team, league League, rng *rand.Rand) ([]Team, error) {
+	groups := league.Groups
+	if len(teams)%groups != 0 || len(teams) < 2*groups {
+		return nil, fmt.Errorf("%w: %d groups need a multiple of %d teams with at least two in every group", errInvalidInput, groups, groups)
+	}
+	size := len(teams) / groups
+	if league.GroupQualifiers > size {
+		return nil, fmt.Errorf("%w: groups of %d teams cannot qualify %d teams each", errInvalidInput, size, league.GroupQualifiers)
+	}
+	if league.BestPlaced > 0 && league.GroupQualifiers == size {
+		return nil, fmt.Errorf("%w: groups of %d teams have no team placed below the %d qualifiers", errInvalidInput, size, league.GroupQualifiers)
+	}
+
+	seeded := slices.Clone(teams)
+	slices.SortStableFunc(seeded, func(a, b Team) int { return cmp.Compare(b.Strength, a.Strength) })
+	drawn := make([]Team, 0, len(seeded))
+	for pot := 0; pot < size; pot++ {
+		potTeams := seeded[pot*groups : (pot+1)*groups]
+		rng.Shuffle(len(potTeams), func(i, j int) { potTeams[i], potTeams[j] = potTeams[j], potTeams[i] })
+		for g, t := range potTeams {
+			t.Group = g + 1
+			t.Seed = len(drawn) + 1
+			drawn = append(drawn, t)
+		}
+	}
+	return drawn, nil
+}
+
+// groupStageFixtures schedules a round-robin inside every group, all groups playing their matchdays in the same weeks
+func groupStageFixtures(teams []Team, doubleRoundRobin bool) []Match {
+	var fixtures []Match
+	for _, group := range teamsByGroup(teams) {
+		fixtures = append(fixtures, generateRoundRobin(group, doubleRoundRobin)...)
+	}
+	slices.SortStableFunc(fixtures, func(a, b Match) int { return cmp.Compare(a.Week, b.Week) })
+	return fixtures
+}
+
+// teamsByGroup splits the teams of a tournament season by group, in group order
+func teamsByGroup(teams []Team) [][]Team {
+	var groups [][]Team
+	for _, t := range teams {
+		if t.Group < 1 {
+			continue
+		}
+		for len(groups) < t.Group {
+			groups = append(groups, nil)
+		}
+		groups[t.Group-1] = append(groups[t.Group-1], t)
+	}
+	return groups
+}
+
+// groupName letters the groups from A
+func groupName(group int) string {
+	return string(rune('A' + group - 1))
+}
+
+// knockoutWeek is the first week after the group stage
+func knockoutWeek(matches []Match) int {
+	week := 0
+	for _, m := range matches {
+		if m.Round == 0 {
+			week = max(week, m.Week)
+		}
+	}
+	return week + 1
+}
+
+// TournamentHandler handles the request for the group tables and the knockout bracket
+func TournamentHandler(tournamentService TournamentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state, err := tournamentService.State()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, state)
+	}
+}
+
+// PlayTournamentRoundHandler handles the request to play the next group stage week or knockout round
+func PlayTournamentRoundHandler(tournamentService TournamentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		seed, err := requestSeed(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		stage, state, err := tournamentService.PlayRound(newRNG(seed))
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message":    fmt.Sprintf("%s played successfully", stage),
+			"tournament": state,
+			"seed":       seed,
+		})
+	}
+}
+
+// TournamentProbabilitiesHandler handles the request for every team's odds of qualifying from its group and advancing in the knockout
+func TournamentProbabilitiesHandler(tournamentService TournamentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		seed, err := requestSeed(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		simulations, err := requestSimulations(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		probabilities, err := tournamentService.Probabilities(newRNG(seed), simulations)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"probabilities": probabilities,
+			"seed":          seed,
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// groupTeams returns teams 1..n with strength falling with the id
+func groupTeams(n int) []Team {
+	teams := make([]Team, n)
+	for i := range teams {
+		teams[i] = Team{ID: i + 1, Name: fmt.Sprintf("Team %d", i+1), Strength: 100 - i}
+	}
+	return teams
+}
+
+func TestDrawGroupsTakesOneTeamOfEveryPot(t *testing.T) {
+	for _, groups := range []int{2, 3, 4, 6} {
+		for seed := int64(1); seed <= 20; seed++ {
+			teams := groupTeams(groups * 4)
+			drawn, err := drawGroups(teams, League{Groups: groups, GroupQualifiers: 2}, newRNG(seed))
+			if err != nil {
+				t.Fatalf("%d groups: %v", groups, err)
+			}
+			if len(drawn) != len(teams) {
+				t.Fatalf("%d groups: drew %d teams, want %d", groups, len(drawn), len(teams))
+			}
+			for g, group := range teamsByGroup(drawn) {
+				if len(group) != 4 {
+					t.Fatalf("%d groups: group %s has %d teams", groups, groupName(g+1), len(group))
+				}
+				// Pot p holds the teams ranked p*groups+1 .. (p+1)*groups by strength
+				for pot, team := range group {
+					if got := (team.ID - 1) / groups; got != pot {
+						t.Errorf("%d groups, seed %d: group %s slot %d holds team %d from pot %d", groups, seed, groupName(g+1), pot, team.ID, got)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestDrawGroupsRejectsImpossibleGroups(t *testing.T) {
+	tests := []struct {
+		name   string
+		teams  int
+		league League
+	}{
+		{"teams not a multiple of the groups", 7, League{Groups: 2, GroupQualifiers: 1}},
+		{"one team per group", 4, League{Groups: 4, GroupQualifiers: 1}},
+		{"more qualifiers than teams", 6, League{Groups: 2, GroupQualifiers: 4}},
+		{"best placed below a full group", 6, League{Groups: 2, GroupQualifiers: 3, BestPlaced: 1}},
+	}
+	for _, tt := range tests {
+		if _, err := drawGroups(groupTeams(tt.teams), tt.league, newRNG(1)); err == nil {
+			t.Errorf("%s: drew groups without an error", tt.name)
+		}
+	}
+}
+
+// firstRoundGroups checks every first round tie of the bracket built from the qualifiers and returns the groups of its two teams
+func firstRoundGroups(t *testing.T, qualified []Team) [][2]int {
+	t.Helper()
+	group := make(map[int]int, len(qualified))
+	for _, q := range qualified {
+		group[q.ID] = q.Group
+	}
+	var pairs [][2]int
+	for _, tie := range buildCupBracket(qualified, nil, 1).Rounds[0].Ties {
+		if tie.Bye {
+			continue
+		}
+		pairs = append(pairs, [2]int{group[tie.Home.TeamID], group[tie.Away.TeamID]})
+	}
+	return pairs
+}
+
+func TestKeepGroupsApartPairsWinnersWithOtherRunnersUp(t *testing.T) {
+	// Ranked A1, B1, B2, A2: the plain bracket 1-4, 2-3 would pair A1 with A2 and B1 with B2
+	qualified := []Team{
+		{ID: 1, Name: "A1", Group: 1},
+		{ID: 2, Name: "B1", Group: 2},
+		{ID: 3, Name: "B2", Group: 2},
+		{ID: 4, Name: "A2", Group: 1},
+	}
+	seeded := keepGroupsApart(qualified)
+	for _, pair := range firstRoundGroups(t, seeded) {
+		if pair[0] == pair[1] {
+			t.Fatalf("group %s meets itself in the first round: %+v", groupName(pair[0]), seeded)
+		}
+	}
+	// The group winners keep the top seeds and only the runners-up swap
+	if got := []string{seeded[0].Name, seeded[1].Name, seeded[2].Name, seeded[3].Name}; !slices.Equal(got, []string{"A1", "B1", "A2", "B2"}) {
+		t.Errorf("seeded %v, want [A1 B1 A2 B2]", got)
+	}
+	for i, s := range seeded {
+		if s.Seed != i+1 {
+			t.Errorf("team %s has seed %d at position %d", s.Name, s.Seed, i+1)
+		}
+	}
+}
+
+func TestKeepGroupsApartForEveryTournamentShape(t *testing.T) {
+	shapes := []struct{ groups, qualifiers, bestPlaced int }{
+		{2, 2, 0}, {2, 2, 1}, {3, 2, 2}, {4, 2, 0}, {4, 1, 0}, {6, 2, 4}, {8, 2, 0}, {4, 3, 0}, {3, 1, 1},
+	}
+	for _, shape := range shapes {
+		// Every permutation of the groups within each place is a possible ranking of the qualifiers
+		for order := 0; order < 20; order++ {
+			rng := newRNG(int64(order))
+			var qualified []Team
+			for place := 0; place <= shape.qualifiers; place++ {
+				groups := rng.Perm(shape.groups)
+				if place == shape.qualifiers {
+					groups = groups[:shape.bestPlaced]
+				}
+				for _, g := range groups {
+					qualified = append(qualified, Team{ID: place*100 + g + 1, Name: fmt.Sprintf("%s%d", groupName(g+1), place+1), Group: g + 1})
+				}
+			}
+
+			seeded := keepGroupsApart(qualified)
+			ids := func(teams []Team) []int {
+				var ids []int
+				for _, t := range teams {
+					ids = append(ids, t.ID)
+				}
+				slices.Sort(ids)
+				return ids
+			}
+			if !slices.Equal(ids(seeded), ids(qualified)) {
+				t.Fatalf("%+v: seeding lost or duplicated teams", shape)
+			}
+			for _, pair := range firstRoundGroups(t, seeded) {
+				if pair[0] == pair[1] {
+					t.Errorf("%+v order %d: group %s meets itself in the first round", shape, order, groupName(pair[0]))
+				}
+			}
+		}
+	}
+}
+
+func TestQualifiersCompareGroupsWithoutHeadToHead(t *testing.T) {
+	settings := benchmarkSettings()
+	settings.GroupTiebreakers = []Tiebreaker{tiebreakH2HPoints, tiebreakGoalDifference}
+	service := &MyTournamentService{settings: settings, league: League{Groups: 2, GroupQualifiers: 1, BestPlaced: 1}}
+
+	// Both runners-up have 4 points, B2 with the better goal difference. Head-to-head points would score both 0
+	// and leave them in group order, putting A2 through.
+	tables := [][]Team{
+		{{ID: 1, Name: "A1", Points: 9}, {ID: 2, Name: "A2", Points: 4, GoalDiff: -1}, {ID: 3, Name: "A3"}},
+		{{ID: 4, Name: "B1", Points: 7}, {ID: 5, Name: "B2", Points: 4, GoalDiff: 3}, {ID: 6, Name: "B3"}},
+	}
+	qualified := service.qualifiers(tables)
+	if len(qualified) != 3 {
+		t.Fatalf("got %d qualifiers, want 3", len(qualified))
+	}
+	if !slices.ContainsFunc(qualified, func(q Team) bool { return q.Name == "B2" }) {
+		t.Errorf("B2 should go through on goal difference: %+v", qualified)
+	}
+	// With one bye the two teams of group B would meet, so B1 takes the bye and A1 plays B2
+	if got := []string{qualified[0].Name, qualified[1].Name, qualified[2].Name}; !slices.Equal(got, []string{"B1", "A1", "B2"}) {
+		t.Errorf("seeded %v, want [B1 A1 B2]", got)
+	}
+	for _, q := range qualified {
+		if q.DecidedBy == string(tiebreakH2HPoints) {
+			t.Errorf("cross-group ranking of %s used %s", q.Name, tiebreakH2HPoints)
+		}
+	}
+}
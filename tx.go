@@ -0,0 +1,129 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// errConflict is returned when a concurrent request holds the season locks for too long
+var errConflict = errors.New("League is being updated by another request, please retry")
+
+// queryer is implemented by both *sql.DB and *sql.Tx
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// withTx runs fn inside a transaction, committing on success and rolling back on any error
+func withTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return asConflict(err)
+	}
+	return asConflict(tx.Commit())
+}
+
+// lockSeason takes a row lock on the season so that writers touching it run one at a time, and returns its status.
+// It must be the first statement of a writing transaction to keep the lock order identical everywhere.
+// SQLite has no row locks, its transactions are opened with BEGIN IMMEDIATE instead (see sqliteDSN).
+func lockSeason(q queryer, dialect string, seasonID int) (string, error) {
+	query := "SELECT status FROM seasons WHERE id = ?"
+	if dialect != "sqlite" {
+		query += " FOR UPDATE"
+	}
+	var status string
+	err := q.QueryRow(query, seasonID).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", errSeasonNotFound
+	}
+	return status, err
+}
+
+// lockLeagues takes row locks on leagues in id order, every league when no id is given,
+// so that two transactions cannot both find a league without an active season and start one.
+// SQLite transactions are serialized by BEGIN IMMEDIATE instead.
+func lockLeagues(q queryer, dialect string, ids []int) error {
+	if dialect == "sqlite" {
+		return nil
+	}
+	query := "SELECT id FROM leagues"
+	args := make([]interface{}, len(ids))
+	if len(ids) > 0 {
+		query += " WHERE id IN (?" + strings.Repeat(", ?", len(ids)-1) + ")"
+		for i, id := range ids {
+			args[i] = id
+		}
+	}
+	rows, err := q.Query(query+" ORDER BY id FOR UPDATE", args...)
+	if err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// asConflict maps MySQL lock wait timeouts and deadlocks, and SQLite busy errors, to errConflict
+func asConflict(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1205, 1213: // ER_LOCK_WAIT_TIMEOUT, ER_LOCK_DEADLOCK
+			return errConflict
+		}
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() & 0xff { // primary result code
+		case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+			return errConflict
+		}
+	}
+	return err
+}
+
+// asNameTaken maps a duplicate key on the unique team name index to errTeamNameTaken
+func asNameTaken(err error, name string) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 { // ER_DUP_ENTRY
+		return fmt.Errorf("%w: %s", errTeamNameTaken, name)
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return fmt.Errorf("%w: %s", errTeamNameTaken, name)
+	}
+	return err
+}
+
+// sqliteDSN builds the connection string for a SQLite database file.
+// Writers wait for each other for up to five seconds before failing with errConflict.
+func sqliteDSN(path string) string {
+	return "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)&_txlock=immediate"
+}
+
+// errorStatus picks the HTTP status for an error returned by a service
+func errorStatus(err error) int {
+	if errors.Is(err, errConflict) || errors.Is(err, errSeasonArchived) || errors.Is(err, errSeasonNotFinished) ||
+		errors.Is(err, errSeasonEnded) || errors.Is(err, errNoFixtures) ||
+		errors.Is(err, errTeamNameTaken) || errors.Is(err, errTeamHasResults) {
+		return http.StatusConflict
+	}
+	if errors.Is(err, errMatchNotFound) || errors.Is(err, errTeamNotFound) || errors.Is(err, errDeductionNotFound) ||
+		errors.Is(err, errLeagueNotFound) || errors.Is(err, errSeasonNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, errInvalidInput) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}